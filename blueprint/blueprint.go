@@ -3,13 +3,21 @@ package blueprint
 
 import (
 	"encoding/json"
+	"image"
+	// Decoders of the palette images.
+	_ "image/jpeg"
+	_ "image/png"
 	"io/ioutil"
 	"math"
+	"math/cmplx"
+	"os"
 	"strings"
 
 	rand7i "github.com/7i/rand"
 
 	"github.com/karlek/wasabi/coloring"
+	"github.com/karlek/wasabi/dd"
+	"github.com/karlek/wasabi/formula"
 	"github.com/karlek/wasabi/fractal"
 	"github.com/karlek/wasabi/iro"
 	"github.com/karlek/wasabi/mandel"
@@ -22,10 +30,15 @@ import (
 // Blueprint contains the settings and options needed to render a fractal.
 type Blueprint struct {
 	Iterations float64 // Number of iterations.
-	Bailout    float64 // Squared radius of the function domain. Most commonly set to 4, but it's important for planes other than Zrzi.
+	Bailout    float64 // Squared radius of the function domain. Most commonly set to 4, but it's important for planes other than Zrzi. Defaults to the bailout of the complex function.
 	Tries      float64 // The number of orbit attempts calculated by: tries * (width * height)
 
 	Coloring string // Coloring method for the orbits.
+	Bands    []Band // Length bands of the bands coloring, colored by the gradient color of the same index. The iterations are raised to the longest band, and each orbit is added to every band it fits in, e.g. a nebulabrot with the bands [{"Max": 5000}, {"Max": 500}, {"Max": 50}] and a red, green and blue gradient.
+
+	Texture       string  // Path to the image of the texture coloring, which adds the colors of the image at the points of the orbits which fall inside it.
+	TextureCenter Complex // Center of the texture in the complex plane.
+	TextureSize   float64 // Width of the texture in the complex plane, where the height keeps the aspect ratio of the image. Defaults to 4.
 
 	DrawPath    bool  // Draw the path between points in the orbit.
 	PathPoints  int64 // The number of intermediate points to use for interpolation.
@@ -35,12 +48,17 @@ type Blueprint struct {
 	Png, Jpg       bool   // Image output format.
 	OutputFilename string // Output filename without (file extension).
 
-	CacheHistograms   bool // Cache the histograms by saving them to a file.
-	MultipleExposures bool // Render the image with multiple exposures.
-	PlotImportance    bool // Create an image of the sampling points color graded by their importance.
+	CacheHistograms    bool    // Cache the histograms by saving them to a file.
+	CheckpointInterval float64 // Seconds between saved checkpoints, from which the render can be resumed. Disabled if zero.
+	PreviewInterval    float64 // Seconds between preview images of the unfinished render, saved as <out>-preview. Disabled if zero.
+	PreviewTries       float64 // Share of the orbit attempts between preview images, e.g. 0.1 for every 10%. Disabled if zero.
+	MultipleExposures  bool    // Render the image with multiple exposures.
+	PlotImportance     bool    // Create an image of the sampling points color graded by their importance.
 
 	Imag      float64 // Offset on the imaginary-value axis.
 	Real      float64 // Offset on the real-value axis.
+	ImagExact string  // Offset on the imaginary-value axis with up to 32 significant digits, for double-double precision. Overrides Imag.
+	RealExact string  // Offset on the real-value axis with up to 32 significant digits, for double-double precision. Overrides Real.
 	Zoom      float64 // Zoom factor.
 	Seed      int64   // Random seed.
 	Threshold float64 // Minimum orbit length to be registered.
@@ -53,9 +71,24 @@ type Blueprint struct {
 	Factor   float64 // Factor is used by the functions in various ways.
 	Exposure float64 // Exposure is a scaling factor applied after the normalization function has been applied.
 
-	RegisterMode string // How the fractal will capture orbits. The different modes are: anti, primitive and escapes.
+	RegisterMode     string  // How the fractal will capture orbits. The different modes are: anti, primitive, escapes, newton, which registers the orbits of Newton's method for the roots of the complex function that converge, e.g. of the formula "z^3 - 1", colored by their roots with the basin coloring, and fieldlines, which registers the orbits that cross a field line.
+	FieldLinesGrowth float64 // Growth g of both parts of a point from the previous point, above which the fieldlines mode has crossed a field line. Defaults to 10000.
+
+	Trap          string  // Orbit trap which the registered orbits must come near: point, circle, line or pickover. Disabled if empty.
+	TrapPoint     Complex // The point of the point trap, center of the circle trap, a point on the line trap or the crossing of the axes of the pickover trap.
+	TrapRadius    float64 // Radius of the circle trap, e.g. 1 for orbits which pass near the unit circle.
+	TrapDirection Complex // Direction of the line trap.
+	TrapDistance  float64 // Largest distance between the trap and the closest point of a registered orbit.
+	TrapWeighted  bool    // Weight the orbits by how close they came to the trap, from full intensity on the trap to none at the distance.
+
+	ComplexFunction string   // The complex function we shall explore: mandelbrot, multibrot, burningship, b1, b2, phoenix, magnet1, magnet2 or a formula such as "z^3 + c*sin(z) - coef".
+	Power           float64  // Exponent d of the multibrot z^d + c, which may be fractional or negative. Defaults to 2.
+	PhoenixP        *Complex // Parameter p of the phoenix z_{n+1} = z_n^2 + c + p*z_{n-1}, the complex function "phoenix". Defaults to -0.5.
+
+	Precision string // Arithmetic of the orbits: double (default) or dd for double-double, which keeps the detail of zooms past about 1e12 but is several times slower. Only the mandelbrot and the multibrots of positive integer powers have double-double variants.
 
-	ComplexFunction string // The complex function we shall explore.
+	CycleDetection string  // Strategy to detect periodic orbits: backoff (default), brent or none. Functions which depend on earlier points of the orbit, e.g. the phoenix, are never checked for cycles.
+	CycleEpsilon   float64 // Largest distance between two points of an orbit which are considered equal by the cycle detection. Zero demands exact repeats, while a small tolerance such as 1e-12 speeds up renders with many converging orbits considerably.
 
 	Plane string // Chose which capital plane we will plot: Crci, Crzi, Zici, Zrci, Zrcr, Zrzi.
 
@@ -63,12 +96,44 @@ type Blueprint struct {
 	Gradient  []iro.RGBA // The color gradient used by the coloring methods.
 	Range     []float64  // The interpolation points for the gradient.
 
-	ZUpdate string // Chose how we shall update Z.
+	PaletteImage  string // Path to an image whose representative colors replace the gradient, with evenly spaced interpolation points.
+	PaletteColors int    // Number of colors taken from the palette image. Defaults to 3.
+	PaletteOrder  string // Order of the colors taken from the palette image: luminance (default), from dark to light, or hue.
+
+	ZUpdate string // Chose how we shall update Z. Defaults to random for the newton register mode, which can't start at origo.
 	CUpdate string // Chose how we shall update C.
 
+	JuliaC    *Complex  // Render the julia set of the parameter c, by fixing c and sampling z. The starting points z are random, or the random points updated by ZUpdate if it's set, and CUpdate is ignored.
+	JuliaPath []Complex // Render an animation of julia sets with the parameters c along the path, saved as <out>-0000 and so on. The fractal of the blueprint itself is the julia set of the first point.
+	Frames    int       // Number of frames of the julia path animation. Defaults to one frame per point of the path.
+
+	Sampler string // Sampling strategy for the orbits: uniform (default), metropolis or importance. The metropolis and importance samplers always sample their points at random, ignoring CUpdate; the points are c, or z of julia sets.
+
+	ImportanceTries      float64 // Share of the tries used by the first pass of the importance sampler, between zero and one. Defaults to 0.1.
+	ImportanceResolution int     // Width and height of the importance samplers distribution, which must be positive. Defaults to 128.
+
+	Accumulation string // How the workers add orbits to the histograms: buffered (default), atomic or locked. Buffered renders with the same seed and tries are identical for any number of workers, but each lane keeps its own histograms, which is costly for large images.
+	Lanes        int    // Number of chunks of orbit attempts rendered ahead of the oldest unfinished chunk, which bounds the memory of buffered renders. Fewer lanes than workers leaves workers idle. Defaults to twice the number of workers.
+
 	Theta float64 // Rotation angle. Experimental option since it demands matrix rotation which slows down the renders considerably on CPU based renders.
 }
 
+// Band is a range of orbit lengths, from Min up to but not including Max. A
+// band without Max ends at the iterations of the blueprint.
+type Band struct {
+	Min, Max float64
+}
+
+// Complex is a complex number.
+type Complex struct {
+	Real, Imag float64
+}
+
+// Complex128 returns the complex number.
+func (c Complex) Complex128() complex128 {
+	return complex(c.Real, c.Imag)
+}
+
 // Parse opens and parses a blueprint json file.
 func Parse(filename string) (blue *Blueprint, err error) {
 	buf, err := ioutil.ReadFile(filename)
@@ -100,27 +165,84 @@ func (b *Blueprint) Fractal() *fractal.Fractal {
 	offset := complex(b.Real, b.Imag)
 
 	// Our way of registering orbits. Either we register the orbits that either converges, diverges or both.
-	registerMode := parseRegistrer(b.RegisterMode)
+	registerMode := parseRegistrer(b.RegisterMode, b.FieldLinesGrowth)
+	if b.Trap != "" {
+		if b.TrapDistance <= 0 {
+			logrus.Fatalln("invalid trap distance:", b.TrapDistance)
+		}
+		registerMode = mandel.Trapped(registerMode, b.parseTrap(), b.TrapDistance, b.TrapWeighted)
+	}
 
 	// Get the complex function to find orbits with.
-	f := parseComplexFunctionFlag(b.ComplexFunction)
+	f := parseComplexFunctionFlag(b.ComplexFunction, b.Power, b.PhoenixP)
+	// Newton's method iterates its steps instead of the function.
+	if strings.ToLower(b.RegisterMode) == "newton" {
+		if f.DZ == nil {
+			logrus.Fatalln("invalid complex function: Newton's method needs a derivative with respect to z:", b.ComplexFunction)
+		}
+		f = mandel.Function{F: mandel.NewtonStep(f.F, f.DZ), Bailout: f.Bailout}
+	}
+
+	// The julia set of the blueprint, if any.
+	julia := b.JuliaC
+	if julia == nil && len(b.JuliaPath) > 0 {
+		julia = &b.JuliaPath[0]
+	}
 
-	z := parseZandC(b.ZUpdate)
-	c := parseZandC(b.CUpdate)
+	var z, c func(complex128, *rand7i.ComplexRNG) complex128
+	if julia != nil {
+		// Julia sets fix c and sample z at random.
+		z = fractal.SampledPoint
+		if b.ZUpdate != "" {
+			z = parseZandC(b.ZUpdate)
+		}
+		c = fractal.RandomPoint
+	} else if strings.ToLower(b.RegisterMode) == "newton" {
+		// Newton's method from origo starts at the critical point of
+		// functions like z^3 - 1, where the step is infinite, so every orbit
+		// is discarded.
+		switch strings.ToLower(b.ZUpdate) {
+		case "":
+			z = fractal.RandomPoint
+		case "origo":
+			logrus.Fatalln("invalid z strategy: Newton's method from origo starts at a critical point, use random instead")
+		default:
+			z = parseZandC(b.ZUpdate)
+		}
+		c = parseZandC(b.CUpdate)
+	} else {
+		z = parseZandC(b.ZUpdate)
+		c = parseZandC(b.CUpdate)
+	}
 
-	colors := iro.ToColors(b.Gradient)
-	method := coloring.NewColoring(b.BaseColor, parseModeFlag(b.Coloring), colors, b.Range)
+	colors, stops := iro.ToColors(b.Gradient), b.Range
+	if b.PaletteImage != "" {
+		colors, stops = b.parsePalette()
+	}
+	method := coloring.NewColoring(b.BaseColor, parseModeFlag(b.Coloring), colors, stops)
+	iterations := int64(b.Iterations)
+	if method.Mode() == coloring.Bands {
+		method.Bands = parseBands(b.Bands, len(colors), iterations)
+		for _, band := range method.Bands {
+			if band.Max > iterations {
+				iterations = band.Max
+			}
+		}
+	}
+	if method.Mode() == coloring.ImageTrap {
+		method.Texture = b.parseTexture()
+	}
 
 	// Fill our histogram bins of the orbits.
-	return fractal.New(
+	frac := fractal.New(
 		b.Width,
 		b.Height,
-		int64(b.Iterations),
+		iterations,
 		method,
 		coefficient,
 		b.Bailout,
 		parsePlane(b.Plane),
-		f,
+		f.F,
 		b.Zoom,
 		offset,
 		b.PlotImportance,
@@ -132,10 +254,95 @@ func (b *Blueprint) Fractal() *fractal.Fractal {
 		b.Theta,
 		z, c,
 		int64(b.Threshold))
+	frac.Reject, frac.FuncDC, frac.NewIterator = f.Reject, f.DC, f.NewIterator
+	frac.Precision = parsePrecision(b.Precision)
+	if frac.Precision == fractal.DoubleDouble {
+		if f.DD == nil {
+			logrus.Fatalln("invalid precision: the complex function has no double-double variant:", b.ComplexFunction)
+		}
+		frac.FuncDD = f.DD
+		frac.Offset, frac.OffsetLo = parseExactOffset(b.RealExact, b.ImagExact, frac.Offset)
+	}
+	if julia != nil {
+		frac.Julia, frac.JuliaC = true, julia.Complex128()
+	}
+	if frac.Bailout == 0 {
+		frac.Bailout = f.Bailout
+	}
+	frac.Sampler = parseSampler(b.Sampler)
+	frac.ImportanceTries = b.ImportanceTries
+	if frac.ImportanceTries == 0 {
+		frac.ImportanceTries = 0.1
+	}
+	// Both passes of the importance sampler need some of the tries.
+	if frac.ImportanceTries < 0 || frac.ImportanceTries >= 1 {
+		logrus.Fatalln("invalid importance tries: the share of the first pass must be between zero and one:", b.ImportanceTries)
+	}
+	frac.ImportanceResolution = b.ImportanceResolution
+	if frac.ImportanceResolution == 0 {
+		frac.ImportanceResolution = 128
+	}
+	if frac.ImportanceResolution < 0 {
+		logrus.Fatalln("invalid importance resolution:", b.ImportanceResolution)
+	}
+	frac.Accumulation = parseAccumulation(b.Accumulation)
+	frac.CycleDetection = parseCycleDetection(b.CycleDetection)
+	frac.Epsilon = b.CycleEpsilon
+	frac.Lanes = b.Lanes
+	if strings.ToLower(b.RegisterMode) == "newton" {
+		// The basins are numbered by the roots of the function of c zero, or
+		// of the julia set.
+		frac.Roots = mandel.NewtonRoots(frac.JuliaC, frac)
+	}
+	if method.Mode() == coloring.Basin && frac.Roots == nil {
+		logrus.Fatalln("invalid coloring: the basin coloring needs the newton register mode, which converges to roots")
+	}
+	return frac
+}
+
+// JuliaFrames returns the parameters c of the frames of the julia path
+// animation, evenly spaced along the path.
+func (b *Blueprint) JuliaFrames() []complex128 {
+	if len(b.JuliaPath) == 0 {
+		return nil
+	}
+	frames := b.Frames
+	if frames == 0 {
+		frames = len(b.JuliaPath)
+	}
+	cs := make([]complex128, frames)
+	if len(b.JuliaPath) == 1 || frames == 1 {
+		for i := range cs {
+			cs[i] = b.JuliaPath[0].Complex128()
+		}
+		return cs
+	}
+
+	// Cumulative length of the path at each of its points.
+	dists := make([]float64, len(b.JuliaPath))
+	for i := 1; i < len(b.JuliaPath); i++ {
+		dists[i] = dists[i-1] + cmplx.Abs(b.JuliaPath[i].Complex128()-b.JuliaPath[i-1].Complex128())
+	}
+	length := dists[len(dists)-1]
+
+	seg := 1
+	for i := range cs {
+		d := length * float64(i) / float64(frames-1)
+		for seg < len(dists)-1 && dists[seg] < d {
+			seg++
+		}
+		from, to := b.JuliaPath[seg-1].Complex128(), b.JuliaPath[seg].Complex128()
+		var t float64
+		if span := dists[seg] - dists[seg-1]; span > 0 {
+			t = (d - dists[seg-1]) / span
+		}
+		cs[i] = from + complex(t, 0)*(to-from)
+	}
+	return cs
 }
 
 // parseRegisterMode parses the _registerer_ string to a fractal orbit registrer.
-func parseRegistrer(registrer string) mandel.Registrer {
+func parseRegistrer(registrer string, growth float64) mandel.Registrer {
 	// Choose buddhabrot registrer.
 	switch strings.ToLower(registrer) {
 	case "anti", "converge", "converges":
@@ -144,12 +351,93 @@ func parseRegistrer(registrer string) mandel.Registrer {
 		return mandel.Primitive
 	case "escapes", "escape":
 		return mandel.Escaped
+	case "newton":
+		return mandel.Newton
+	case "fieldlines", "field":
+		if growth == 0 {
+			growth = mandel.FieldLinesGrowth
+		}
+		return mandel.FieldLines(growth)
 	default:
 		logrus.Fatalln("Unknown registrer:", registrer)
 	}
 	return mandel.Escaped
 }
 
+// parsePalette returns the colors extracted from the palette image and their
+// evenly spaced stops.
+func (b *Blueprint) parsePalette() ([]iro.Color, []float64) {
+	n := b.PaletteColors
+	if n == 0 {
+		n = 3
+	}
+	if n < 2 {
+		logrus.Fatalln("invalid palette colors: the gradient needs at least two colors:", n)
+	}
+	var order iro.Order
+	switch strings.ToLower(b.PaletteOrder) {
+	case "", "luminance":
+		order = iro.ByLuminance
+	case "hue":
+		order = iro.ByHue
+	default:
+		logrus.Fatalln("invalid palette order:", b.PaletteOrder)
+	}
+	f, err := os.Open(b.PaletteImage)
+	if err != nil {
+		logrus.Fatalln("invalid palette image:", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		logrus.Fatalln("invalid palette image:", err)
+	}
+	colors, stops, err := iro.PaletteStops(img, n, order)
+	if err != nil {
+		logrus.Fatalf("invalid palette image: %s: %v", b.PaletteImage, err)
+	}
+	return colors, stops
+}
+
+// parseTexture loads the texture of the texture coloring.
+func (b *Blueprint) parseTexture() *coloring.Texture {
+	if b.Texture == "" {
+		logrus.Fatalln("invalid texture: the texture coloring needs an image")
+	}
+	size := b.TextureSize
+	if size == 0 {
+		size = 4
+	}
+	t, err := coloring.LoadTexture(b.Texture, b.TextureCenter.Complex128(), size)
+	if err != nil {
+		logrus.Fatalln("invalid texture:", err)
+	}
+	return t
+}
+
+// parseTrap parses the orbit trap of the blueprint to a function which returns
+// the squared distance between a point and the trap.
+func (b *Blueprint) parseTrap() func(complex128) float64 {
+	p := b.TrapPoint.Complex128()
+	switch strings.ToLower(b.Trap) {
+	case "point":
+		return mandel.Point(p)
+	case "circle":
+		return mandel.Circle(p, b.TrapRadius)
+	case "line":
+		dir := b.TrapDirection.Complex128()
+		if dir == 0 {
+			logrus.Fatalln("invalid trap direction: the line trap needs a direction")
+		}
+		return mandel.Line(p, dir)
+	case "pickover", "cross":
+		return mandel.Pickover(-p)
+	default:
+		logrus.Fatalln("invalid orbit trap:", b.Trap)
+	}
+	return nil
+}
+
 // parseFunctionFlag parses the _fun_ string to a color scaling function.
 func parseFunctionFlag(f string) func(float64, float64) float64 {
 	switch strings.ToLower(f) {
@@ -195,20 +483,38 @@ func parsePlane(plane string) func(complex128, complex128) complex128 {
 }
 
 // parseComplexFunctionFlag parses the _function_ string to a complex function.
-func parseComplexFunctionFlag(function string) func(complex128, complex128, complex128) complex128 {
-	switch strings.ToLower(function) {
-	case "mandelbrot":
-		return mandel.Mandelbrot
-	case "burningship":
-		return mandel.BurningShip
-	case "b1":
-		return mandel.B1
-	case "b2":
-		return mandel.B2
-	default:
-		logrus.Fatalln("invalid complex function:", function)
+// The multibrot is created with the given _power_, or as the mandelbrot if
+// it's zero, and the phoenix with the parameter _phoenix_, or -0.5 if it's
+// nil. Other functions than the named are parsed as formulas, e.g.
+// "z^3 + c*sin(z)".
+func parseComplexFunctionFlag(function string, power float64, phoenix *Complex) mandel.Function {
+	if strings.ToLower(function) == "multibrot" {
+		if power == 0 {
+			power = 2
+		}
+		return mandel.NewMultibrot(power)
 	}
-	return mandel.Mandelbrot
+	if strings.ToLower(function) == "phoenix" {
+		p := complex(-0.5, 0)
+		if phoenix != nil {
+			p = phoenix.Complex128()
+		}
+		return mandel.Function{NewIterator: mandel.NewPhoenix(p), Bailout: 4}
+	}
+	if f, ok := mandel.Functions[strings.ToLower(function)]; ok {
+		return f
+	}
+	expr, err := formula.Parse(function)
+	if err != nil {
+		logrus.Fatalln("invalid complex function:", err)
+	}
+	f := mandel.Function{F: expr.Func(), Bailout: 4}
+	// Formulas of functions without complex derivatives can't be used by
+	// Newton's method.
+	if d, err := expr.Derivative("z"); err == nil {
+		f.DZ = d.Func()
+	}
+	return f
 }
 
 // parseModeFlag parses the _mode_ string to a coloring function.
@@ -224,12 +530,118 @@ func parseModeFlag(mode string) coloring.Mode {
 		return coloring.OrbitLength
 	case "path":
 		return coloring.Path
+	case "bands", "nebula":
+		return coloring.Bands
+	case "texture", "trap":
+		return coloring.ImageTrap
+	case "basin":
+		return coloring.Basin
 	default:
 		logrus.Fatalln("invalid coloring function:", mode)
 	}
 	return coloring.IterationCount
 }
 
+// parseBands converts the length bands of the blueprint to coloring bands,
+// ending at the iterations if they lack a maximum length. Each band needs a
+// color of the gradient.
+func parseBands(bands []Band, colors int, iterations int64) []coloring.Band {
+	if len(bands) == 0 {
+		logrus.Fatalln("invalid bands: the bands coloring needs at least one band")
+	}
+	if len(bands) > colors {
+		logrus.Fatalf("invalid bands: %d bands but only %d gradient colors", len(bands), colors)
+	}
+	cbs := make([]coloring.Band, len(bands))
+	for i, band := range bands {
+		cbs[i] = coloring.Band{Min: int64(band.Min), Max: int64(band.Max)}
+		if band.Max == 0 {
+			cbs[i].Max = iterations
+		}
+		if cbs[i].Min >= cbs[i].Max {
+			logrus.Fatalf("invalid bands: band %d is empty, %v", i, band)
+		}
+	}
+	return cbs
+}
+
+// parseSampler parses the _sampler_ string to a sampling strategy.
+func parseSampler(sampler string) fractal.Sampler {
+	switch strings.ToLower(sampler) {
+	case "", "uniform":
+		return fractal.Uniform
+	case "metropolis", "mh":
+		return fractal.Metropolis
+	case "importance":
+		return fractal.ImportanceSampling
+	default:
+		logrus.Fatalln("invalid sampler:", sampler)
+	}
+	return fractal.Uniform
+}
+
+// parseAccumulation parses the _accumulation_ string to a method of adding
+// orbits to the histograms.
+func parseAccumulation(accumulation string) fractal.Accumulation {
+	switch strings.ToLower(accumulation) {
+	case "", "buffered":
+		return fractal.Buffered
+	case "atomic":
+		return fractal.Atomic
+	case "locked":
+		return fractal.Locked
+	default:
+		logrus.Fatalln("invalid accumulation:", accumulation)
+	}
+	return fractal.Buffered
+}
+
+// parsePrecision parses the _precision_ string to the arithmetic of the orbits.
+func parsePrecision(precision string) fractal.Precision {
+	switch strings.ToLower(precision) {
+	case "", "double":
+		return fractal.Double
+	case "dd", "double-double", "doubledouble":
+		return fractal.DoubleDouble
+	default:
+		logrus.Fatalln("invalid precision:", precision)
+	}
+	return fractal.Double
+}
+
+// parseExactOffset parses the _re_ and _im_ strings to the high and low parts
+// of the offset. Empty strings keep the parts of the offset.
+func parseExactOffset(re, im string, offset complex128) (hi, lo complex128) {
+	parse := func(s string, f float64) dd.Float {
+		if s == "" {
+			return dd.New(f)
+		}
+		x, err := dd.Parse(s)
+		if err != nil {
+			logrus.Fatalln("invalid offset:", err)
+		}
+		return x
+	}
+	r, i := parse(re, real(offset)), parse(im, imag(offset))
+	return complex(r.Hi, i.Hi), complex(r.Lo, i.Lo)
+}
+
+// parseCycleDetection parses the _detection_ string to a strategy for detecting
+// periodic orbits.
+func parseCycleDetection(detection string) fractal.CycleDetection {
+	switch strings.ToLower(detection) {
+	case "", "backoff":
+		return fractal.Backoff
+	case "brent":
+		return fractal.Brent
+	case "none":
+		return fractal.NoDetection
+	default:
+		logrus.Fatalln("invalid cycle detection:", detection)
+	}
+	return fractal.Backoff
+}
+
 // parseZandC choses the sampling methods for our original points.
 func parseZandC(mode string) func(complex128, *rand7i.ComplexRNG) complex128 {
 	switch strings.ToLower(mode) {
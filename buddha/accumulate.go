@@ -0,0 +1,53 @@
+package buddha
+
+import (
+	"image"
+
+	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/histo"
+)
+
+// buffer returns a copy of the fractal which a chunk of orbit attempts is
+// rendered into. With buffered accumulation the copy has its own histograms,
+// which are added to the fractal by reduce. Otherwise the copy shares the
+// histograms of the fractal.
+func buffer(frac *fractal.Fractal) *fractal.Fractal {
+	f := *frac
+	if frac.Accumulation == fractal.Buffered {
+		f.R = histo.New(frac.Width, frac.Height)
+		f.G = histo.New(frac.Width, frac.Height)
+		f.B = histo.New(frac.Width, frac.Height)
+		if frac.PlotImportance {
+			f.Importance = histo.New(frac.Width, frac.Height)
+		}
+	}
+	return &f
+}
+
+// reduce adds the histograms of a buffer to the fractal and clears them. The
+// buffers are reduced in the order of their chunks, which keeps buffered
+// renders reproducible.
+func reduce(frac *fractal.Fractal, buf *fractal.Fractal) {
+	if frac.Accumulation != fractal.Buffered {
+		return
+	}
+	histo.Reduce(frac.R, buf.R)
+	histo.Reduce(frac.G, buf.G)
+	histo.Reduce(frac.B, buf.B)
+	if frac.PlotImportance {
+		histo.Reduce(frac.Importance, buf.Importance)
+	}
+}
+
+// add adds v to the bin (x, y) of the histogram with the accumulation method of
+// the fractal.
+func add(h histo.Histo, pt image.Point, v float64, frac *fractal.Fractal) {
+	switch frac.Accumulation {
+	case fractal.Atomic:
+		histo.AddAtomic(h, pt.X, pt.Y, v)
+	case fractal.Locked:
+		histo.AddLocked(h, pt.X, pt.Y, v)
+	default:
+		h[pt.X][pt.Y] += v
+	}
+}
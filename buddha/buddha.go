@@ -3,6 +3,7 @@
 package buddha
 
 import (
+	"context"
 	"image"
 	"math"
 	"sync"
@@ -10,103 +11,321 @@ import (
 
 	rand7i "github.com/7i/rand"
 
-	"github.com/karlek/progress/barcli"
 	"github.com/karlek/wasabi/coloring"
 	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/histo"
+	"github.com/karlek/wasabi/progress"
 )
 
 // FillHistograms creates a number of workers which finds orbits and stores
-// their points in a histogram.
-func FillHistograms(frac *fractal.Fractal, workers int) float64 {
-	bar, _ := barcli.New(int(frac.Tries * float64(frac.Width*frac.Height)))
-	go func(bar *barcli.Bar) {
-		for {
-			if bar.Done() {
-				return
-			}
-			bar.Print()
-			time.Sleep(1000 * time.Millisecond)
-		}
-	}(bar)
-
-	wg := new(sync.WaitGroup)
-	wg.Add(workers)
+// their points in a histogram. It returns the ratio between registered points
+// and orbit attempts, and the number of completed attempts. If the context is
+// cancelled the workers stop and the histograms contain the orbits of the
+// completed chunks, together with the error of the context.
+func FillHistograms(ctx context.Context, frac *fractal.Fractal, workers int) (float64, int64, error) {
+	return Resume(ctx, frac, NewCheckpoint(), workers, nil, nil)
+}
 
+// Resume continues to fill the histograms of the fractal with a number of
+// workers from the state of a render, which is updated as the render
+// progresses. The progress is reported to rep, unless it's nil. If save isn't
+// nil, it's called as chunks of orbit attempts complete, when the histograms
+// are consistent with the state. A render can therefore be resumed from the
+// saved state and histograms, with any number of workers. The histograms and
+// the state are consistent when Resume returns too, also if the context was
+// cancelled, so an interrupted render can be saved and resumed.
+func Resume(ctx context.Context, frac *fractal.Fractal, cp *Checkpoint, workers int, rep progress.Reporter, save func(*Checkpoint) error) (float64, int64, error) {
 	orbitTries := int64(frac.Tries * float64(frac.Width*frac.Height))
 
-	share := orbitTries / int64(workers)
-	totChan := make(chan int64)
+	if rep == nil {
+		rep = progress.Silent{}
+	}
+	m := newMeter(workers, cp.Tries, orbitTries)
+	reportCtx, stop := context.WithCancel(ctx)
+	reported := make(chan struct{})
+	go func() {
+		m.report(reportCtx, rep)
+		close(reported)
+	}()
+
+	var err error
+	switch frac.Sampler {
+	case fractal.Metropolis:
+		err = metropolisSampling(ctx, frac, cp, workers, orbitTries, m, save)
+	case fractal.ImportanceSampling:
+		err = importanceSampling(ctx, frac, cp, workers, orbitTries, m, save)
+	default:
+		err = run(ctx, frac, cp, workers, orbitTries, 0, arbitrary, m, save)
+	}
+	stop()
+	<-reported
+	cp.Stats.Elapsed += time.Since(m.start).Seconds()
+
+	if cp.Tries == 0 {
+		return 0, 0, err
+	}
+	return float64(cp.Total) / float64(cp.Tries), cp.Tries, err
+}
+
+// sampler is a worker which tries to find share number of orbits in a chunk,
+// and counts its attempts with the counter of the chunk. It returns the total
+// number of registered points and the number of completed attempts, which is
+// less than share if the context was cancelled.
+type sampler func(ctx context.Context, frac *fractal.Fractal, rng *rand7i.ComplexRNG, share int64, cnt *counter) (total, tries int64)
+
+// job is a chunk of orbit attempts handed to a worker, with the buffer it's
+// rendered into.
+type job struct {
+	k   int64
+	buf *fractal.Fractal
+}
+
+// result is a rendered chunk of orbit attempts.
+type result struct {
+	job
+	total, tries int64
+	cnt          *counter
+}
+
+// run divides the orbit attempts of a sampling pass into chunks, which the
+// workers take in order. Each chunk is rendered into a buffer, and the buffers
+// are added to the fractal in the order of their chunks while the workers
+// continue with the next chunks, after which the checkpoint is updated and
+// saved. A buffered render therefore only depends on the seed and the number
+// of tries. At most window chunks are rendered ahead of the oldest unfinished
+// chunk, which bounds the memory of the buffers. The histograms of renders
+// which aren't buffered are shared by the chunks, and are only consistent with
+// the checkpoint when no chunks are rendered; the workers wait for each other
+// every window chunks to save them. The seeds of the chunks are offset by the
+// number of chunks in previous passes.
+//
+// When the render stops, the chunks which were interrupted and the chunks
+// after them are discarded, since the checkpoint can't resume them. The
+// chunks of unbuffered renders can't be taken back from the histograms, so
+// their workers finish the chunks they have started instead. It returns the
+// error of the context if it was cancelled, or the error of save.
+func run(ctx context.Context, frac *fractal.Fractal, cp *Checkpoint, workers int, tries, offset int64, sample sampler, m *meter, save func(*Checkpoint) error) error {
+	n := chunks(tries)
+	// The streams of the completed chunks, which is all of them if the pass
+	// was completed before it was resumed.
+	frac.Streams = offset + cp.Chunk
+	if cp.Chunk >= n {
+		return nil
+	}
+	window := numLanes(frac, workers)
+	buffered := frac.Accumulation == fractal.Buffered
 
-	for n := 0; n < workers; n++ {
-		// Our worker channel to send our orbits on!
-		rng := rand7i.NewComplexRNG(int64(n+1) + frac.Seed)
-		go arbitrary(totChan, frac, &rng, share, wg, bar)
+	// The workers of unbuffered renders aren't interrupted.
+	work := ctx
+	if !buffered {
+		work = context.Background()
 	}
-	wg.Wait()
+	jobs := make(chan job)
+	results := make(chan result, window)
+	wg := new(sync.WaitGroup)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(g *gauge) {
+			defer wg.Done()
+			for j := range jobs {
+				began := time.Now()
+				rng := rand7i.NewComplexRNG(chunkSeed(frac, offset+j.k))
+				cnt := &counter{gauge: g}
+				total, done := sample(work, j.buf, &rng, chunkSize(j.k, tries), cnt)
+				cnt.stats.Time("sample", time.Since(began))
+				results <- result{job: j, total: total, tries: done, cnt: cnt}
+			}
+		}(&m.gauges[w])
+	}
+	defer wg.Wait()
+	defer close(jobs)
 
-	var totals int64
-	for tot := range totChan {
-		workers--
-		totals += tot
-		if workers == 0 {
-			close(totChan)
+	// The buffers which aren't used by any chunk, and the number of buffers.
+	var free []*fractal.Fractal
+	var bufs int
+	// The next chunk to hand out, the next chunk to reduce, and the chunk the
+	// workers of unbuffered renders wait at.
+	next, head, barrier := cp.Chunk, cp.Chunk, n
+	if !buffered && save != nil {
+		barrier = next + int64(window)
+	}
+	pending := make(map[int64]result)
+	done := ctx.Done()
+	// Whether no more chunks are handed out, and whether the chunks are
+	// discarded since an earlier chunk was interrupted.
+	var stopped, discard bool
+	err := ctx.Err()
+	if err != nil {
+		stopped = true
+	}
+	for {
+		// Hand out the next chunk, unless we're stopping or waiting.
+		var send chan job
+		var j job
+		if !stopped && next < n && next < barrier && next-head < int64(window) {
+			if len(free) == 0 {
+				free, bufs = append(free, buffer(frac)), bufs+1
+			}
+			send, j = jobs, job{k: next, buf: free[len(free)-1]}
+		}
+		if send == nil && next == head {
 			break
 		}
+
+		select {
+		case send <- j:
+			free = free[:len(free)-1]
+			next++
+			continue
+		case <-done:
+			stopped, done = true, nil
+			err = ctx.Err()
+			continue
+		case res := <-results:
+			pending[res.k] = res
+		}
+
+		// Add the rendered chunks to the fractal in order. The checkpoint
+		// only advances past the completed chunks.
+		advanced := false
+		for res, ok := pending[head]; ok; res, ok = pending[head] {
+			delete(pending, head)
+			head++
+			if res.tries < chunkSize(res.k, tries) {
+				stopped, discard = true, true
+			}
+			// The buffers of the discarded chunks aren't reused, since no
+			// more chunks are handed out.
+			if discard {
+				continue
+			}
+			began := time.Now()
+			reduce(frac, res.buf)
+			cp.Stats.Time("reduce", time.Since(began))
+			free = append(free, res.buf)
+			cp.Total += res.total
+			cp.Tries += res.tries
+			cp.Stats.Add(&res.cnt.stats)
+			cp.Chunk, advanced = head, true
+			frac.Streams = offset + cp.Chunk
+			// The state of the samplers only keeps the completed chunks.
+			if res.cnt.grid != nil {
+				histo.Merge(res.cnt.grid, cp.Grid)
+			}
+			cp.Seeds = append(cp.Seeds, res.cnt.seeds...)
+		}
+		if !buffered && head == barrier {
+			barrier += int64(window)
+			if barrier > n {
+				barrier = n
+			}
+		}
+		// The histograms of unbuffered renders are only consistent with the
+		// checkpoint when no chunks are rendered. The caller saves the state
+		// of a stopped render when it returns.
+		if !advanced || stopped || save == nil || (!buffered && next != head) {
+			continue
+		}
+		began := time.Now()
+		if err = save(cp); err != nil {
+			stopped = true
+		}
+		cp.Stats.Time("save", time.Since(began))
+	}
+	if err == nil && cp.Chunk < n {
+		err = ctx.Err()
 	}
-	bar.SetMax()
-	bar.Print()
+	return err
+}
 
-	return float64(totals) / float64(orbitTries)
+// numLanes returns the number of chunks rendered ahead of the oldest
+// unfinished chunk by a number of workers. It defaults to twice the number of
+// workers, which keeps them busy while the chunks are added to the fractal.
+func numLanes(frac *fractal.Fractal, workers int) int {
+	lanes := frac.Lanes
+	if lanes < 1 {
+		lanes = 2 * workers
+	}
+	if lanes < 1 {
+		lanes = 1
+	}
+	return lanes
+}
+
+// cancelled returns true if the context has been cancelled.
+func cancelled(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
 }
 
 // arbitrary will try to find orbits in the complex function by choosing a
 // random point in it's domain and iterating it a number of times to see if it
 // converges or diverges.
-func arbitrary(totChan chan int64, frac *fractal.Fractal, rng *rand7i.ComplexRNG, share int64, wg *sync.WaitGroup, bar *barcli.Bar) {
-	orbit := &fractal.Orbit{Points: make([]complex128, frac.Iterations)}
-	var z, c complex128
-	var total, i int64
-	for i = 0; i < share; i++ {
+func arbitrary(ctx context.Context, frac *fractal.Fractal, rng *rand7i.ComplexRNG, share int64, cnt *counter) (total, i int64) {
+	orbit := fractal.NewOrbit(frac.Iterations)
+	var p complex128
+	for i = 0; i < share && !cancelled(ctx); i++ {
 		// Our random points which, hopefully, will create an orbit!
-		c = frac.C(c, rng)
-		z = frac.Z(c, rng)
+		p = frac.C(p, rng)
+		z, c := frac.Start(p, rng)
 		orbit.C = c
 
 		length := Attempt(z, c, orbit, frac)
 		total += length
+		// Count the attempt for the progress reports and statistics.
+		cnt.attempt(orbit, frac, length)
 		if IsLongOrbit(length, frac) {
-			i += searchNearby(z, orbit, frac, &total, bar)
+			i += searchNearby(z, orbit, frac, &total, cnt)
 		}
 
 		// Plot sampling map.
 		if frac.PlotImportance {
 			importance(z, c, frac, length)
 		}
-
-		// Increase progress bar.
-		bar.Inc()
 	}
-	wg.Done()
-	go func() { totChan <- total }()
+	return total, i
 }
 
 // Attempt tries to find valid orbit from the points z and c and returns the length of the orbit inside the image space.
 func Attempt(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
+	iterations := iterate(z, c, orbit, frac)
+	if iterations == -1 {
+		return 0
+	}
+	return register(iterations, orbit, frac)
+}
+
+// iterate calculates the orbit of the points z and c and returns the number of
+// iterations it completed. Orbits which aren't registered or are too short
+// return -1.
+func iterate(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
 	// Iterations completed by the complex function.
+	orbit.Outcome, orbit.Intensity = fractal.Unknown, 1
 	iterations := frac.Register(z, c, orbit, frac)
+	orbit.Length = iterations
 	// Reject unregistered orbits.
 	if iterations == -1 {
-		return 0
+		return -1
 	}
 	// Cull too short orbits.
 	if iterations < frac.Threshold {
-		return 0
+		return -1
 	}
+	return iterations
+}
 
-	// The number of pixels we registered inside the image space.
-	var pixels int64
+// register adds the points of an iterated orbit to the histograms with the
+// coloring method of the fractal and returns the number of pixels registered
+// inside the image space. The points outside the image space are counted by
+// the orbit.
+func register(iterations int64, orbit *fractal.Orbit, frac *fractal.Fractal) (pixels int64) {
+	orbit.Outside = 0
 	switch frac.Method.Mode() {
-	case coloring.Modulo:
+	case coloring.Modulo, coloring.Bands:
 		fallthrough
 	case coloring.IterationCount:
 		pixels = registerOrbit(iterations, orbit, frac)
@@ -116,6 +335,21 @@ func Attempt(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64
 		pixels = registerField(iterations, orbit, frac)
 	case coloring.Path:
 		pixels = registerPaths(iterations, orbit, frac)
+	case coloring.ImageTrap:
+		pixels = registerTexture(iterations, orbit, frac)
+	case coloring.Basin:
+		pixels = registerBasin(iterations, orbit, frac)
+	}
+	return pixels
+}
+
+// inView returns the number of points of an iterated orbit which are inside
+// the image space.
+func inView(iterations int64, orbit *fractal.Orbit, frac *fractal.Fractal) (pixels int64) {
+	for i := range orbit.Points[:iterations] {
+		if _, ok := frac.PointLo(orbit.Points[i], orbit.Low(int64(i)), orbit.C); ok {
+			pixels++
+		}
 	}
 	return pixels
 }
@@ -128,34 +362,42 @@ func IsLongOrbit(length int64, frac *fractal.Fractal) bool {
 }
 
 // searchNearby samples points from nearby a point which rendered a long orbit
-// with increasingly smaller larger steps out from the point.
-func searchNearby(z complex128, orbit *fractal.Orbit, frac *fractal.Fractal, total *int64, bar *barcli.Bar) (i int64) {
+// with increasingly smaller larger steps out from the point. The nearby points
+// are c, unless the fractal is a julia set where they're z.
+func searchNearby(z complex128, orbit *fractal.Orbit, frac *fractal.Fractal, total *int64, cnt *counter) (i int64) {
 	h, tol := 1e-15, 1e-2
 	var orbits int64
 
+	p := orbit.C
+	if frac.Julia {
+		p = z
+	}
 outer:
 	for ; h < tol; h *= 1e1 {
-		cr, ci := real(orbit.C), imag(orbit.C)
-
-		cs := []complex128{
-			complex(cr+h, ci),
-			complex(cr-h, ci),
-			complex(cr, ci+h),
-			complex(cr, ci-h),
-			complex(cr+h, ci+h),
-			complex(cr-h, ci-h),
-			complex(cr+h, ci-h),
-			complex(cr-h, ci+h),
-		}
+		pr, pi := real(p), imag(p)
 
-		for _, cprim := range cs {
-			bar.Inc()
+		ps := []complex128{
+			complex(pr+h, pi),
+			complex(pr-h, pi),
+			complex(pr, pi+h),
+			complex(pr, pi-h),
+			complex(pr+h, pi+h),
+			complex(pr-h, pi-h),
+			complex(pr+h, pi-h),
+			complex(pr-h, pi+h),
+		}
 
-			length := Attempt(z, cprim, orbit, frac)
+		for _, pprim := range ps {
+			zprim, cprim := z, pprim
+			if frac.Julia {
+				zprim, cprim = pprim, orbit.C
+			}
+			length := Attempt(zprim, cprim, orbit, frac)
 			(*total) += length
+			cnt.attempt(orbit, frac, length)
 
 			if frac.PlotImportance {
-				importance(z, orbit.C, frac, length)
+				importance(zprim, orbit.C, frac, length)
 			}
 
 			if !IsLongOrbit(length, frac) {
@@ -164,17 +406,20 @@ outer:
 			orbits++
 		}
 	}
+	cnt.stats.Nearby += orbits
 	return orbits
 }
 
-// registerPoint converts the complex point into an image coordinate and
-// increases it's histogram values. Points outside the image canvas are
-// ignored.
-func registerPoint(z complex128, orbit *fractal.Orbit, frac *fractal.Fractal, red, green, blue float64) int64 {
-	if pt, ok := frac.Point(z, orbit.C); ok {
-		increase(pt, red, green, blue, frac)
+// registerPoint converts the i-th point of the orbit into an image coordinate
+// and increases it's histogram values weighted by the orbit. Points outside
+// the image canvas are only counted by the orbit.
+func registerPoint(i int64, orbit *fractal.Orbit, frac *fractal.Fractal, red, green, blue float64) int64 {
+	if pt, ok := frac.PointLo(orbit.Points[i], orbit.Low(i), orbit.C); ok {
+		w := orbit.Weight * orbit.Intensity
+		increase(pt, w*red, w*green, w*blue, frac)
 		return 1
 	}
+	orbit.Outside++
 	return 0
 }
 
@@ -182,13 +427,13 @@ func registerPoint(z complex128, orbit *fractal.Orbit, frac *fractal.Fractal, re
 // histograms.
 func increase(pt image.Point, red, green, blue float64, frac *fractal.Fractal) {
 	if red != 0 {
-		frac.R[pt.X][pt.Y] += red
+		add(frac.R, pt, red, frac)
 	}
 	if green != 0 {
-		frac.G[pt.X][pt.Y] += green
+		add(frac.G, pt, green, frac)
 	}
 	if blue != 0 {
-		frac.B[pt.X][pt.Y] += blue
+		add(frac.B, pt, blue, frac)
 	}
 }
 
@@ -197,9 +442,9 @@ func increase(pt image.Point, red, green, blue float64, frac *fractal.Fractal) {
 // from the gradient.
 func registerColoredOrbit(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum int64) {
 	// Get color from gradient based on iteration count of the orbit.
-	for i, p := range orbit.Points[:it] {
+	for i := range orbit.Points[:it] {
 		red, green, blue := frac.Method.Get(int64(i), frac.Iterations)
-		sum += registerPoint(p, orbit, frac, red, green, blue)
+		sum += registerPoint(int64(i), orbit, frac, red, green, blue)
 	}
 	return sum
 }
@@ -209,18 +454,44 @@ func registerColoredOrbit(it int64, orbit *fractal.Orbit, frac *fractal.Fractal)
 func registerOrbit(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum int64) {
 	// Get color from gradient based on iteration count of the orbit.
 	red, green, blue := frac.Method.Get(it, frac.Iterations)
-	for _, p := range orbit.Points[:it] {
-		sum += registerPoint(p, orbit, frac, red, green, blue)
+	for i := range orbit.Points[:it] {
+		sum += registerPoint(int64(i), orbit, frac, red, green, blue)
+	}
+	return sum
+}
+
+// registerTexture registers the points of an orbit which fall inside the
+// texture with the colors of the texture at the points.
+func registerTexture(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum int64) {
+	for i, z := range orbit.Points[:it] {
+		col, ok := frac.Method.Texture.At(z)
+		if !ok {
+			continue
+		}
+		sum += registerPoint(int64(i), orbit, frac, col.R, col.G, col.B)
+	}
+	return sum
+}
+
+// registerBasin registers the points of an orbit of Newton's method with the
+// color of the root it converged to.
+func registerBasin(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum int64) {
+	red, green, blue := frac.Method.Basin(orbit.Root, len(frac.Roots), it, frac.Iterations)
+	for i := range orbit.Points[:it] {
+		sum += registerPoint(int64(i), orbit, frac, red, green, blue)
 	}
 	return sum
 }
 
 // importance registers the importance of point (z, c) based on its length in a
-// histogram.
+// histogram. The sampled point of julia sets is z.
 func importance(z, c complex128, frac *fractal.Fractal, length int64) {
+	if frac.Julia {
+		c = z
+	}
 	imp := fractal.Importance(frac)
 	if p, ok := imp.Point(z, c); ok {
 		inc := float64(length) / float64(frac.Iterations)
-		frac.Importance[p.X][p.Y] += inc
+		add(frac.Importance, p, inc, frac)
 	}
 }
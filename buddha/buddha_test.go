@@ -0,0 +1,320 @@
+package buddha
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/karlek/wasabi/blueprint"
+	"github.com/karlek/wasabi/coloring"
+	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/histo"
+	"github.com/karlek/wasabi/iro"
+)
+
+// samplers are the sampling strategies of the tests.
+var samplers = []string{"uniform", "metropolis", "importance"}
+
+// testFractal returns a small render of four chunks of orbit attempts.
+func testFractal(sampler, accumulation string) *fractal.Fractal {
+	return testBlueprint(sampler, accumulation).Fractal()
+}
+
+// testBlueprint returns the blueprint of testFractal.
+func testBlueprint(sampler, accumulation string) *blueprint.Blueprint {
+	return &blueprint.Blueprint{
+		Iterations:      100,
+		Tries:           4 * chunkTries / (32 * 32),
+		Coloring:        "iteration",
+		Width:           32,
+		Height:          32,
+		Zoom:            1,
+		RealCoefficient: 1,
+		Seed:            1,
+		Function:        "exp",
+		RegisterMode:    "escapes",
+		ComplexFunction: "mandelbrot",
+		Plane:           "zrzi",
+		Gradient:        []iro.RGBA{{R: 1, G: 0.5, B: 0.25, A: 1}, {R: 0.25, G: 0.5, B: 1, A: 1}},
+		Range:           []float64{0, 1},
+		ZUpdate:         "origo",
+		CUpdate:         "random",
+		Sampler:         sampler,
+		Accumulation:    accumulation,
+	}
+}
+
+// render fills the histograms of a new test fractal with a number of workers,
+// and returns it with the state of the completed render.
+func render(t *testing.T, sampler, accumulation string, workers int) (*fractal.Fractal, *Checkpoint) {
+	frac, cp := testFractal(sampler, accumulation), NewCheckpoint()
+	if _, _, err := Resume(context.Background(), frac, cp, workers, nil, nil); err != nil {
+		t.Fatalf("%s: %v", sampler, err)
+	}
+	return frac, cp
+}
+
+// counts fails the test if the renders of the checkpoints didn't count the
+// same orbit attempts, points and outcomes. The times differ.
+func counts(t *testing.T, name string, got, want *Checkpoint) {
+	t.Helper()
+	if got.Tries != want.Tries || got.Total != want.Total {
+		t.Errorf("%s: got %d tries of %d points, want %d tries of %d points", name, got.Tries, got.Total, want.Tries, want.Total)
+	}
+	g, w := got.Stats, want.Stats
+	g.Elapsed, g.CPUSeconds, w.Elapsed, w.CPUSeconds = 0, nil, 0, nil
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s: got the statistics %+v, want %+v", name, g, w)
+	}
+}
+
+// compare fails the test if the histograms of the fractals differ by more than
+// the relative tolerance.
+func compare(t *testing.T, name string, got, want *fractal.Fractal, tol float64) {
+	t.Helper()
+	for _, h := range []struct {
+		channel   string
+		got, want histo.Histo
+	}{{"red", got.R, want.R}, {"green", got.G, want.G}, {"blue", got.B, want.B}} {
+		for x := range h.want {
+			for y, v := range h.want[x] {
+				if d := math.Abs(h.got[x][y] - v); d > tol*math.Abs(v) {
+					t.Fatalf("%s: %s bin (%d, %d) is %v, want %v", name, h.channel, x, y, h.got[x][y], v)
+				}
+			}
+		}
+	}
+	if histo.Max(want.R) == 0 {
+		t.Fatalf("%s: the render is black", name)
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	for _, sampler := range samplers {
+		want, wantCp := render(t, sampler, "buffered", 1)
+		for _, workers := range []int{2, 7} {
+			got, gotCp := render(t, sampler, "buffered", workers)
+			compare(t, sampler, got, want, 0)
+			counts(t, sampler, gotCp, wantCp)
+		}
+	}
+
+	// The last chunk gets the remainder of the tries, for any number of
+	// workers. The uniform sampler counts the attempts of its searches near
+	// long orbits too, which may end its chunks past their tries.
+	for _, sampler := range samplers {
+		for _, workers := range []int{1, 3} {
+			frac := testFractal(sampler, "buffered")
+			want := int64(3*chunkTries + 101)
+			frac.Tries = float64(want) / float64(frac.Width*frac.Height)
+			_, got, err := FillHistograms(context.Background(), frac, workers)
+			if err != nil || got < want || (sampler != "uniform" && got != want) {
+				t.Errorf("%s, %d workers: completed %d tries, want %d (%v)", sampler, workers, got, want, err)
+			}
+		}
+	}
+}
+
+// snapshot is a saved render, like the checkpoints of wasabi.
+type snapshot struct {
+	State   *Checkpoint
+	R, G, B histo.Histo
+}
+
+func TestResume(t *testing.T) {
+	for _, sampler := range samplers {
+		want, _ := render(t, sampler, "buffered", 3)
+		for _, accumulation := range []string{"buffered", "atomic"} {
+			name := sampler + " " + accumulation
+
+			// Interrupt the render in the middle of its last pass, while other
+			// chunks are rendered, and save it once it has stopped.
+			ctx, cancel := context.WithCancel(context.Background())
+			frac := testFractal(sampler, accumulation)
+			frac.Lanes = 2
+			cp := NewCheckpoint()
+			save := func(cp *Checkpoint) error {
+				if cp.Tries >= chunkTries {
+					cancel()
+				}
+				return nil
+			}
+			if _, _, err := Resume(ctx, frac, cp, 2, nil, save); err != context.Canceled {
+				t.Fatalf("%s: interrupted render returned %v", name, err)
+			}
+			var saved bytes.Buffer
+			if err := gob.NewEncoder(&saved).Encode(snapshot{cp, frac.R, frac.G, frac.B}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Resume the saved render with another number of workers.
+			var snap snapshot
+			if err := gob.NewDecoder(&saved).Decode(&snap); err != nil {
+				t.Fatal(err)
+			}
+			got := testFractal(sampler, accumulation)
+			got.R, got.G, got.B = snap.R, snap.G, snap.B
+			if _, _, err := Resume(context.Background(), got, snap.State, 4, nil, nil); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			// The additions of atomic renders are made in any order, which
+			// rounds them differently.
+			compare(t, name, got, want, 1e-9)
+			if got.Streams != want.Streams {
+				t.Errorf("%s: resumed render sampled %d streams, want %d", name, got.Streams, want.Streams)
+			}
+
+			// Resuming the completed render renders nothing, but still records
+			// its streams.
+			again := testFractal(sampler, accumulation)
+			if _, _, err := Resume(context.Background(), again, snap.State, 1, nil, nil); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if again.Streams != want.Streams {
+				t.Errorf("%s: completed render sampled %d streams, want %d", name, again.Streams, want.Streams)
+			}
+		}
+	}
+}
+
+func TestAccumulation(t *testing.T) {
+	for _, sampler := range samplers {
+		want, wantCp := render(t, sampler, "buffered", 4)
+		for _, accumulation := range []string{"atomic", "locked"} {
+			got, gotCp := render(t, sampler, accumulation, 4)
+			// The additions of atomic and locked renders are made in any
+			// order, which rounds them differently.
+			compare(t, sampler+" "+accumulation, got, want, 1e-9)
+			// The chunks and their orbits are the same, whichever way they're
+			// added to the histograms.
+			counts(t, sampler+" "+accumulation, gotCp, wantCp)
+		}
+	}
+}
+
+func TestRegisterTexture(t *testing.T) {
+	// A 2×2 texture of red and green above blue and a transparent pixel,
+	// covering the square from -1-1i to 1+1i.
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 255, 0, 255})
+	img.Set(0, 1, color.NRGBA{0, 0, 255, 255})
+	frac := testFractal("uniform", "buffered")
+	frac.Method.Texture = coloring.NewTexture(img, 0, 2)
+
+	golden := []struct {
+		z       complex128
+		r, g, b float64
+	}{
+		{complex(-0.5, -0.5), 1, 0, 0},
+		{complex(0.5, -0.5), 0, 1, 0},
+		{complex(-0.5, 0.5), 0, 0, 1},
+		// The transparent pixel and the points outside the texture aren't
+		// registered.
+		{complex(0.5, 0.5), 0, 0, 0},
+		{complex(1.5, 0), 0, 0, 0},
+	}
+	orbit := fractal.NewOrbit(int64(len(golden)))
+	for i, g := range golden {
+		orbit.Points[i] = g.z
+	}
+	if n := registerTexture(int64(len(golden)), orbit, frac); n != 3 {
+		t.Errorf("registered %d points, want 3", n)
+	}
+	for _, g := range golden {
+		pt, ok := frac.Point(g.z, 0)
+		if !ok {
+			t.Fatalf("%v is outside the image", g.z)
+		}
+		if r, gr, b := frac.R[pt.X][pt.Y], frac.G[pt.X][pt.Y], frac.B[pt.X][pt.Y]; r != g.r || gr != g.g || b != g.b {
+			t.Errorf("%v: got (%v, %v, %v), want (%v, %v, %v)", g.z, r, gr, b, g.r, g.g, g.b)
+		}
+	}
+}
+
+func TestRegisterBasin(t *testing.T) {
+	// A gradient from red to blue colors the first of two roots red and the
+	// second blue.
+	frac := testFractal("uniform", "buffered")
+	colors := iro.ToColors([]iro.RGBA{{R: 1, A: 1}, {B: 1, A: 1}})
+	frac.Method = coloring.NewColoring(iro.RGBA{}, coloring.Basin, colors, []float64{0, 1})
+	frac.Roots = []complex128{-1, 1}
+
+	golden := []struct {
+		z    complex128
+		root int
+	}{
+		{complex(-0.5, 0), 0},
+		{complex(0.5, 0), 1},
+		// Orbits which converged to none of the roots have the base color.
+		{complex(0, 0.5), -1},
+	}
+	orbit := fractal.NewOrbit(1)
+	for _, g := range golden {
+		orbit.Points[0], orbit.Root = g.z, g.root
+		registerBasin(1, orbit, frac)
+		pt, ok := frac.Point(g.z, 0)
+		if !ok {
+			t.Fatalf("%v is outside the image", g.z)
+		}
+		r, b := frac.R[pt.X][pt.Y], frac.B[pt.X][pt.Y]
+		switch {
+		case g.root == 0 && !(r > 0.5 && r > 2*b):
+			t.Errorf("root 0: got red %v and blue %v, want mostly red", r, b)
+		case g.root == 1 && !(b > 0.5 && b > 2*r):
+			t.Errorf("root 1: got red %v and blue %v, want mostly blue", r, b)
+		case g.root == -1 && (r != 0 || b != 0):
+			t.Errorf("unknown root: got red %v and blue %v, want the black base", r, b)
+		}
+	}
+}
+
+func TestTextureRender(t *testing.T) {
+	// A texture of red on the left and blue on the right of the imaginary
+	// axis, covering the image.
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 0, 255, 255})
+	path := filepath.Join(t.TempDir(), "texture.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := testBlueprint("uniform", "buffered")
+	b.Coloring, b.Texture, b.TextureSize = "texture", path, 8
+	frac := b.Fractal()
+	if _, _, err := FillHistograms(context.Background(), frac, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	// The orbits only add the colors of the texture where they are.
+	left, _ := frac.Point(complex(-1, 0), 0)
+	right, _ := frac.Point(complex(1, 0), 0)
+	for x := range frac.R {
+		for y := range frac.R[x] {
+			if frac.G[x][y] != 0 {
+				t.Fatalf("pixel (%d, %d) is green", x, y)
+			}
+			if x <= left.X && frac.B[x][y] != 0 || x >= right.X && frac.R[x][y] != 0 {
+				t.Fatalf("pixel (%d, %d) has the color of the other side: red %v, blue %v", x, y, frac.R[x][y], frac.B[x][y])
+			}
+		}
+	}
+	if histo.Max(frac.R) == 0 || histo.Max(frac.B) == 0 {
+		t.Errorf("the render lacks the colors of the texture")
+	}
+}
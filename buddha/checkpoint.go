@@ -0,0 +1,91 @@
+package buddha
+
+import (
+	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/histo"
+)
+
+// chunkTries is the number of orbit attempts in a chunk. The orbit attempts of
+// a render are divided into chunks, each with its own random number generator,
+// which makes the render independent of the number of workers and its state
+// possible to save.
+const chunkTries = 1 << 16
+
+// Checkpoint is the state of a render, from which it can be resumed. The
+// histograms are kept by the fractal.
+type Checkpoint struct {
+	Tries int64       // Number of completed orbit attempts.
+	Total int64       // Number of registered orbit points.
+	Pass  int         // Current pass of a multi-pass sampler.
+	Chunk int64       // Number of completed chunks in the current pass.
+	Grid  histo.Histo // Sampling map of the importance sampler, which covers the domain of the uniform sampler.
+	Seeds []Seed      // Starting points of the chains of the metropolis sampler.
+	Stats Stats       // Statistics of the completed orbit attempts.
+}
+
+// NewCheckpoint returns the initial state of a render.
+func NewCheckpoint() *Checkpoint {
+	return new(Checkpoint)
+}
+
+// chunks returns the number of chunks needed for a number of orbit attempts.
+func chunks(tries int64) int64 {
+	return (tries + chunkTries - 1) / chunkTries
+}
+
+// chunkSize returns the number of orbit attempts of the k:th chunk. The last
+// chunk gets the remainder of the attempts.
+func chunkSize(k, tries int64) int64 {
+	if n := tries - k*chunkTries; n < chunkTries {
+		return n
+	}
+	return chunkTries
+}
+
+const (
+	// gamma is the increment of SplitMix64, derived from the golden ratio.
+	gamma = 0x9e3779b97f4a7c15
+	// gammaInv is the multiplicative inverse of gamma modulo 2^64.
+	gammaInv = 0xf1de83e19937733d
+)
+
+// chunkSeed returns the seed of the random number generator of the k:th chunk
+// of a render. The seeds are the outputs of a SplitMix64 generator seeded by
+// the seed of the fractal, which splits it into independent streams. Renders
+// with different seeds can therefore be merged.
+func chunkSeed(frac *fractal.Fractal, k int64) int64 {
+	seed := int64(mix(mix(uint64(frac.Seed)) + uint64(k+1)*gamma))
+	// A zero seed would be chosen at random.
+	if seed == 0 {
+		return 1
+	}
+	return seed
+}
+
+// StreamsOverlap returns true if renders with the seeds, which have sampled
+// the first n1 and n2 random streams of their seeds, share any of the streams.
+// The streams of all seeds are positions in the same sequence of SplitMix64
+// states, where the k:th stream of a seed is k steps after the mixed seed, so
+// the streams of different seeds may overlap too.
+func StreamsOverlap(seed1, n1, seed2, n2 int64) bool {
+	if n1 <= 0 || n2 <= 0 {
+		return false
+	}
+	first1, first2 := streamPosition(seed1), streamPosition(seed2)
+	// The positions wrap around, like the states.
+	return first2-first1 < uint64(n1) || first1-first2 < uint64(n2)
+}
+
+// streamPosition returns the position of the first random stream of the seed
+// in the sequence of SplitMix64 states.
+func streamPosition(seed int64) uint64 {
+	return mix(uint64(seed))*gammaInv + 1
+}
+
+// mix is the finalizer of SplitMix64, which is a bijection that scrambles the
+// bits of x.
+func mix(x uint64) uint64 {
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
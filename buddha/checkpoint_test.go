@@ -0,0 +1,36 @@
+package buddha
+
+import (
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+func TestStreamsOverlap(t *testing.T) {
+	// The k:th stream of a seed is the state k steps after its position.
+	for _, seed := range []int64{0, 1, -7, 1 << 40} {
+		frac := &fractal.Fractal{Seed: seed}
+		for k := int64(0); k < 4; k++ {
+			state := (streamPosition(seed) + uint64(k)) * gamma
+			if got, want := int64(mix(state)), chunkSeed(frac, k); got != want {
+				t.Errorf("stream %d of seed %d has the seed %d, want %d", k, seed, got, want)
+			}
+		}
+	}
+
+	tests := []struct {
+		seed1, n1, seed2, n2 int64
+		want                 bool
+	}{
+		{1, 10, 1, 10, true},
+		{1, 10, 1, 1, true},
+		{1, 0, 1, 10, false},
+		{1, 1 << 20, 2, 1 << 20, false},
+		{2, 1 << 20, 1, 1 << 20, false},
+	}
+	for _, test := range tests {
+		if got := StreamsOverlap(test.seed1, test.n1, test.seed2, test.n2); got != test.want {
+			t.Errorf("streams %d of seed %d overlap streams %d of seed %d: %v, want %v", test.n1, test.seed1, test.n2, test.seed2, got, test.want)
+		}
+	}
+}
@@ -17,7 +17,7 @@ func registerField(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum i
 		u := orbit.Points[i]
 		v := orbit.Points[j]
 		red, green, blue := angleColor(frac, u, v)
-		sum += registerPoint(u, orbit, frac, red, green, blue)
+		sum += registerPoint(i, orbit, frac, red, green, blue)
 	}
 	return sum
 }
@@ -0,0 +1,170 @@
+package buddha
+
+import (
+	"context"
+	"sort"
+
+	rand7i "github.com/7i/rand"
+
+	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/histo"
+)
+
+// defensive is the share of the sampling distribution which stays uniform, so
+// that points missed by the first pass still can be sampled.
+const defensive = 0.1
+
+// importanceSampling renders the fractal in two passes. The first pass samples
+// points uniformly and records their contribution to the image in a low
+// resolution map. The second pass draws its points from the map as a
+// probability distribution, and divides the contribution of each orbit by its
+// sampling probability to keep the histograms an unbiased estimate.
+func importanceSampling(ctx context.Context, frac *fractal.Fractal, cp *Checkpoint, workers int, tries int64, m *meter, save func(*Checkpoint) error) error {
+	first := int64(float64(tries) * frac.ImportanceTries)
+	if cp.Pass == 0 {
+		if cp.Grid == nil {
+			cp.Grid = histo.New(frac.ImportanceResolution, frac.ImportanceResolution)
+		}
+		if err := run(ctx, frac, cp, workers, first, 0, explore(frac.ImportanceResolution), m, save); err != nil {
+			return err
+		}
+		cp.Pass, cp.Chunk = 1, 0
+	}
+
+	// The chunks of the second pass continue after the chunks of the first,
+	// to use different random streams.
+	dist := newDistribution(cp.Grid)
+	return run(ctx, frac, cp, workers, tries-first, chunks(first), dist.sample, m, save)
+}
+
+// explore returns a worker which samples points uniformly and adds the number
+// of points their orbits registers inside the image space to a grid of the
+// resolution. Each chunk fills its own grid, which is added to the sampling
+// map of the checkpoint together with the histograms of the chunk. The sampled
+// points are the starting points z of julia sets.
+func explore(res int) sampler {
+	return func(ctx context.Context, frac *fractal.Fractal, rng *rand7i.ComplexRNG, share int64, cnt *counter) (total, i int64) {
+		cnt.grid = histo.New(res, res)
+		orbit := fractal.NewOrbit(frac.Iterations)
+		var p complex128
+		for i = 0; i < share && !cancelled(ctx); i++ {
+			p = fractal.RandomPoint(p, rng)
+			z, c := frac.Start(p, rng)
+			orbit.C = c
+
+			length := Attempt(z, c, orbit, frac)
+			total += length
+			x, y := cell(p, res)
+			cnt.grid[x][y] += float64(length)
+
+			// Plot sampling map.
+			if frac.PlotImportance {
+				importance(z, c, frac, length)
+			}
+
+			cnt.attempt(orbit, frac, length)
+		}
+		return total, i
+	}
+}
+
+// cell returns the grid cell of the point c in the domain of the uniform
+// sampler.
+func cell(c complex128, res int) (x, y int) {
+	clamp := func(v int) int {
+		if v < 0 {
+			return 0
+		}
+		if v >= res {
+			return res - 1
+		}
+		return v
+	}
+	x = clamp(int((real(c) + 2) / 4 * float64(res)))
+	y = clamp(int((imag(c) + 2) / 4 * float64(res)))
+	return x, y
+}
+
+// distribution is a piecewise constant probability distribution over the
+// domain of the uniform sampler.
+type distribution struct {
+	res int       // Number of cells along each axis.
+	cdf []float64 // Cumulative probability of the cells.
+}
+
+// newDistribution creates a distribution proportional to the values of the
+// grid, mixed with a uniform distribution.
+func newDistribution(grid histo.Histo) *distribution {
+	res := len(grid)
+	cells := float64(res * res)
+
+	var sum float64
+	for _, col := range grid {
+		for _, v := range col {
+			sum += v
+		}
+	}
+
+	d := &distribution{res: res, cdf: make([]float64, 0, res*res)}
+	var acc float64
+	for _, col := range grid {
+		for _, v := range col {
+			p := defensive / cells
+			if sum > 0 {
+				p += (1 - defensive) * v / sum
+			} else {
+				p = 1 / cells
+			}
+			acc += p
+			d.cdf = append(d.cdf, acc)
+		}
+	}
+	return d
+}
+
+// point draws a point from the distribution and returns it together with the
+// probability of its cell.
+func (d *distribution) point(rng *rand7i.ComplexRNG) (complex128, float64) {
+	last := d.cdf[len(d.cdf)-1]
+	k := sort.SearchFloat64s(d.cdf, uniform(rng)*last)
+	if k >= len(d.cdf) {
+		k = len(d.cdf) - 1
+	}
+	p := d.cdf[k]
+	if k > 0 {
+		p -= d.cdf[k-1]
+	}
+	p /= last
+
+	// A uniformly distributed point inside the cell.
+	u := rng.Complex128Go()
+	size := 4 / float64(d.res)
+	x, y := float64(k/d.res), float64(k%d.res)
+	c := complex(-2+(x+(real(u)+2)/4)*size, -2+(y+(imag(u)+2)/4)*size)
+	return c, p
+}
+
+// sample is a worker which draws its points from the distribution. Each orbit
+// is weighted by the ratio between the uniform probability and the probability
+// of its cell.
+func (d *distribution) sample(ctx context.Context, frac *fractal.Fractal, rng *rand7i.ComplexRNG, share int64, cnt *counter) (total, i int64) {
+	orbit := fractal.NewOrbit(frac.Iterations)
+	cells := float64(len(d.cdf))
+	for i = 0; i < share && !cancelled(ctx); i++ {
+		pt, p := d.point(rng)
+		z, c := frac.Start(pt, rng)
+		orbit.C = c
+		orbit.Weight = 1 / (cells * p)
+
+		length := Attempt(z, c, orbit, frac)
+		total += length
+
+		// Plot sampling map.
+		if frac.PlotImportance {
+			importance(z, c, frac, length)
+		}
+
+		cnt.attempt(orbit, frac, length)
+	}
+	return total, i
+}
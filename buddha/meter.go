@@ -0,0 +1,99 @@
+package buddha
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/histo"
+	"github.com/karlek/wasabi/progress"
+)
+
+// reportInterval is the time between progress reports.
+const reportInterval = time.Second
+
+// counter counts the orbit attempts of a chunk. The progress is counted by
+// the gauge of the worker which renders the chunk, and the statistics are
+// added to the checkpoint together with the histograms of the chunk.
+type counter struct {
+	gauge *gauge
+	stats Stats
+	grid  histo.Histo // Contributions of the sampled points of the first pass of the importance sampler.
+	seeds []Seed      // Starting points of the chains of the first pass of the metropolis sampler.
+}
+
+// attempt counts an orbit attempt which registered pixels points inside the
+// image.
+func (c *counter) attempt(orbit *fractal.Orbit, frac *fractal.Fractal, pixels int64) {
+	atomic.AddInt64(&c.gauge.tries, 1)
+	if pixels > 0 {
+		atomic.AddInt64(&c.gauge.hits, 1)
+	}
+	c.stats.attempt(orbit, frac, pixels)
+}
+
+// gauge counts the orbit attempts of a worker for the progress reports. A
+// gauge is only written by its worker, which keeps the atomic operations
+// uncontended.
+type gauge struct {
+	tries, hits int64
+	// Pad the gauges of the workers to separate cache lines, so the workers
+	// don't invalidate each other's caches.
+	_ [48]byte
+}
+
+// meter measures the progress of a render.
+type meter struct {
+	start  time.Time
+	before int64 // Orbit attempts completed before the render was resumed.
+	max    int64 // Orbit attempts of the whole render.
+	gauges []gauge
+}
+
+// newMeter returns a meter for a render with one gauge per worker.
+func newMeter(workers int, before, max int64) *meter {
+	return &meter{start: time.Now(), before: before, max: max, gauges: make([]gauge, workers)}
+}
+
+// event returns the current progress of the render.
+func (m *meter) event() progress.Event {
+	var tries, hits int64
+	for i := range m.gauges {
+		tries += atomic.LoadInt64(&m.gauges[i].tries)
+		hits += atomic.LoadInt64(&m.gauges[i].hits)
+	}
+	e := progress.Event{
+		Tries:   m.before + tries,
+		Max:     m.max,
+		Elapsed: time.Since(m.start),
+	}
+	if tries > 0 {
+		e.HitRatio = float64(hits) / float64(tries)
+	}
+	if secs := e.Elapsed.Seconds(); secs > 0 {
+		e.Rate = float64(tries) / secs
+	}
+	if left := m.max - e.Tries; left > 0 && e.Rate > 0 {
+		e.ETA = time.Duration(float64(left) / e.Rate * float64(time.Second))
+	}
+	return e
+}
+
+// report sends the progress of the render to the reporter until the context
+// is done, and a final event after that.
+func (m *meter) report(ctx context.Context, rep progress.Reporter) {
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e := m.event()
+			e.Done = true
+			rep.Report(e)
+			return
+		case <-ticker.C:
+			rep.Report(m.event())
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package buddha
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	rand7i "github.com/7i/rand"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+const (
+	// largeMutation is the probability of proposing an entirely new point
+	// instead of a point close to the current one.
+	largeMutation = 0.2
+	// seedTries is the number of uniformly sampled points of the first pass
+	// for each chain of the second pass.
+	seedTries = 1000
+)
+
+// Seed is a starting point of the chains of the metropolis sampler, chosen
+// among the uniformly sampled points of a chunk of the first pass. Each chunk
+// chooses a number of seeds independently of each other.
+type Seed struct {
+	P, Z, C complex128 // The sampled point, and the starting points of its orbit.
+	Weight  int64      // Contribution of all the points sampled by the chunk.
+	Tries   int64      // Number of points sampled by the chunk.
+}
+
+// metropolisSampling renders the fractal in two passes. The first pass samples
+// points uniformly, and registers their orbits like the uniform sampler. It
+// estimates the average contribution of an orbit, which normalizes the weights
+// of the chains, and picks starting points for the chains in each chunk
+// proportionally to their contribution. The first pass samples seedTries
+// points for each chain, and picks as many starting points as there are
+// chains, which spreads the starting points of the chains. The second pass runs a
+// chain in each chunk with the Metropolis-Hastings algorithm.
+func metropolisSampling(ctx context.Context, frac *fractal.Fractal, cp *Checkpoint, workers int, tries int64, m *meter, save func(*Checkpoint) error) error {
+	first := tries / 100
+	if n := chunks(tries) * seedTries; first < n {
+		first = n
+	}
+	if first > tries {
+		first = tries
+	}
+	// The number of seeds of each chunk of the first pass, to start each
+	// chain of the second pass from its own seed.
+	seeds := (chunks(tries-first) + chunks(first) - 1) / chunks(first)
+	if seeds < 1 {
+		seeds = 1
+	}
+	if cp.Pass == 0 {
+		if err := run(ctx, frac, cp, workers, first, 0, seeder(seeds), m, save); err != nil {
+			return err
+		}
+		cp.Pass, cp.Chunk = 1, 0
+	}
+
+	// The chunks of the second pass continue after the chunks of the first,
+	// to use different random streams.
+	ch := newChain(cp.Seeds)
+	if ch == nil {
+		// No orbit was visible in the image space, and the chains can't be
+		// started.
+		return run(ctx, frac, cp, workers, tries-first, chunks(first), seeder(seeds), m, save)
+	}
+	return run(ctx, frac, cp, workers, tries-first, chunks(first), ch.sample, m, save)
+}
+
+// seeder returns a worker which samples points uniformly and registers their
+// orbits. It picks a number of the points as the seeds of the chunk, each
+// proportionally to the number of orbit points it has inside the image space.
+// The sampled points are the starting points z of julia sets.
+func seeder(seeds int64) sampler {
+	return func(ctx context.Context, frac *fractal.Fractal, rng *rand7i.ComplexRNG, share int64, cnt *counter) (total, i int64) {
+		orbit := fractal.NewOrbit(frac.Iterations)
+		cnt.seeds = make([]Seed, seeds)
+		var weight int64
+		var p complex128
+		for i = 0; i < share && !cancelled(ctx); i++ {
+			p = fractal.RandomPoint(p, rng)
+			z, c := frac.Start(p, rng)
+			orbit.C = c
+
+			var f, pixels int64
+			if it := iterate(z, c, orbit, frac); it != -1 {
+				f = inView(it, orbit, frac)
+				pixels = register(it, orbit, frac)
+			}
+			total += pixels
+			weight += f
+			// Weighted reservoir sampling of each seed.
+			for k := range cnt.seeds {
+				if f > 0 && uniform(rng) < float64(f)/float64(weight) {
+					cnt.seeds[k].P, cnt.seeds[k].Z, cnt.seeds[k].C = p, z, c
+				}
+			}
+
+			// Plot sampling map.
+			if frac.PlotImportance {
+				importance(z, c, frac, pixels)
+			}
+
+			cnt.attempt(orbit, frac, pixels)
+		}
+		for k := range cnt.seeds {
+			cnt.seeds[k].Weight, cnt.seeds[k].Tries = weight, i
+		}
+		return total, i
+	}
+}
+
+// chain runs the chains of the metropolis sampler from the seeds of the first
+// pass.
+type chain struct {
+	seeds []Seed
+	cdf   []float64 // Cumulative weight of the seeds.
+	norm  float64   // Average contribution of a uniformly sampled orbit.
+}
+
+// newChain returns the chains of the seeds, or nil if none of the seeds have
+// any contribution.
+func newChain(seeds []Seed) *chain {
+	ch := &chain{seeds: seeds}
+	var weight, tries int64
+	for _, s := range seeds {
+		weight += s.Weight
+		tries += s.Tries
+		ch.cdf = append(ch.cdf, float64(weight))
+	}
+	if weight == 0 {
+		return nil
+	}
+	ch.norm = float64(weight) / float64(tries)
+	return ch
+}
+
+// sample is a worker which finds orbits with the Metropolis-Hastings
+// algorithm. Instead of choosing every point at random, it mutates the last
+// accepted point and accepts the mutation with a probability proportional to
+// the number of orbit points it registers inside the image space. Since long
+// visible orbits are sampled more often than uniform sampling would, each
+// orbit is weighted with the inverse of its contribution to keep the
+// histograms an unbiased estimate of the buddhabrot.
+func (ch *chain) sample(ctx context.Context, frac *fractal.Fractal, rng *rand7i.ComplexRNG, share int64, cnt *counter) (total, i int64) {
+	cur := fractal.NewOrbit(frac.Iterations)
+	prop := fractal.NewOrbit(frac.Iterations)
+
+	// The chain starts from a seed, which is chosen proportionally to the
+	// contributions of the seeds.
+	last := ch.cdf[len(ch.cdf)-1]
+	k := sort.SearchFloat64s(ch.cdf, uniform(rng)*last)
+	for k < len(ch.seeds)-1 && ch.seeds[k].Weight == 0 {
+		k++
+	}
+	start := ch.seeds[k]
+	curP := start.P
+	cur.C = start.C
+	curIt := iterate(start.Z, start.C, cur, frac)
+	curF := inView(curIt, cur, frac)
+
+	for ; i < share && !cancelled(ctx); i++ {
+		p := mutate(curP, frac, rng)
+		z, c := frac.Start(p, rng)
+		prop.C = c
+		propIt := iterate(z, c, prop, frac)
+		var propF int64
+		if propIt != -1 && inDomain(p) {
+			propF = inView(propIt, prop, frac)
+		}
+
+		// Plot sampling map.
+		if frac.PlotImportance {
+			importance(z, c, frac, propF)
+		}
+
+		// The mutations are symmetric, so the acceptance probability is the
+		// ratio between the contributions.
+		if float64(propF)/float64(curF) > uniform(rng) {
+			cur, prop = prop, cur
+			curP, curIt, curF = p, propIt, propF
+		}
+
+		// The chain samples points proportionally to their contribution, which
+		// we divide away from the orbit.
+		cur.Weight = ch.norm / float64(curF)
+		pixels := register(curIt, cur, frac)
+		total += pixels
+		// Count the orbit registered by the chain, rather than the proposal.
+		cnt.attempt(cur, frac, pixels)
+	}
+	return total, i
+}
+
+// mutate proposes a new point for the chain. Small mutations are taken in a
+// random direction with an exponentially distributed distance, scaled to the
+// size of the image space.
+func mutate(c complex128, frac *fractal.Fractal, rng *rand7i.ComplexRNG) complex128 {
+	if uniform(rng) < largeMutation {
+		return fractal.RandomPoint(c, rng)
+	}
+	// The width of the image space in the complex plane.
+	size := 4 / frac.Zoom
+	r1, r2 := size*1e-4, size*1e-1
+	r := r2 * math.Exp(-math.Log(r2/r1)*uniform(rng))
+	phi := 2 * math.Pi * uniform(rng)
+	return c + complex(r*math.Cos(phi), r*math.Sin(phi))
+}
+
+// inDomain returns true if the point is inside the domain of the uniform
+// sampler. Mutations outside of it have no contribution, otherwise the
+// estimate would differ from the uniform one.
+func inDomain(c complex128) bool {
+	return math.Abs(real(c)) <= 2 && math.Abs(imag(c)) <= 2
+}
+
+// uniform returns a pseudo-random number in the range [0, 1].
+func uniform(rng *rand7i.ComplexRNG) float64 {
+	return (real(rng.Complex128Go()) + 2) / 4
+}
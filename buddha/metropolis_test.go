@@ -0,0 +1,36 @@
+package buddha
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/karlek/wasabi/histo"
+)
+
+// mass returns the sum of the bins of the histogram.
+func mass(h histo.Histo) (sum float64) {
+	for _, col := range h {
+		for _, v := range col {
+			sum += v
+		}
+	}
+	return sum
+}
+
+// TestUnbiased compares the histograms of the samplers which weight their
+// orbits with plain uniform sampling, which the uniform sampler isn't since it
+// searches near long orbits.
+func TestUnbiased(t *testing.T) {
+	want := testFractal("uniform", "buffered")
+	tries := int64(want.Tries * float64(want.Width*want.Height))
+	if err := run(context.Background(), want, NewCheckpoint(), 4, tries, 0, seeder(1), newMeter(4, 0, tries), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, sampler := range []string{"metropolis", "importance"} {
+		got, _ := render(t, sampler, "buffered", 4)
+		if ratio := mass(got.R) / mass(want.R); math.Abs(ratio-1) > 0.1 {
+			t.Errorf("%s: the histograms have %.3f times the mass of uniform sampling", sampler, ratio)
+		}
+	}
+}
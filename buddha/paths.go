@@ -58,6 +58,7 @@ func registerPaths(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) int64
 // registerBezier
 func registerBezier(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum int64) {
 	points := make([]image.Point, frac.BezierLevel+1)
+	w := orbit.Weight * orbit.Intensity
 
 	first := orbit.Points[0]
 
@@ -84,7 +85,7 @@ func registerBezier(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum
 		for p := 0; p <= int(frac.PathPoints)-1; p++ {
 			t := float64(p) / float64(frac.PathPoints)
 			pt := bezier(points, frac.BezierLevel, t)
-			increase(pt, red, green, blue, frac)
+			increase(pt, w*red, w*green, w*blue, frac)
 			sum++
 		}
 		i += j
@@ -95,6 +96,7 @@ func registerBezier(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) (sum
 func registerLinear(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
 	// Get color from gradient based on iteration count of the orbit.
 	red, green, blue := frac.Method.Get(it, frac.Iterations)
+	w := orbit.Weight * orbit.Intensity
 	bresPoints := make([]image.Point, 0, frac.PathPoints)
 	for i := 0; i < int(it)-1; i++ {
 		// Convert the complex point to a pixel coordinate.
@@ -107,7 +109,7 @@ func registerLinear(it int64, orbit *fractal.Orbit, frac *fractal.Fractal) int64
 			continue
 		}
 		for _, pt := range bresenham(a, b, bresPoints) {
-			increase(pt, red, green, blue, frac)
+			increase(pt, w*red, w*green, w*blue, frac)
 		}
 	}
 	return 0
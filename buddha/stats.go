@@ -0,0 +1,156 @@
+package buddha
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+// Stats are statistics of the orbit attempts of a render, which explain how
+// the points of the image were found, or why they weren't.
+type Stats struct {
+	Tries int64 // Orbit attempts.
+
+	// Outcomes of the iterated orbits, as told by the registering function.
+	InBulb    int64 // Points c inside one of the larger bulbs of the mandelbrot, which weren't iterated.
+	Cycles    int64 // Orbits found to be periodic by the cycle detection.
+	Escaped   int64 // Orbits which left the bailout radius.
+	Converged int64 // Orbits which stayed inside the bailout radius for all iterations.
+	Untrapped int64 // Orbits which never came near the orbit trap.
+	Roots     int64 // Orbits of Newton's method which converged to a root.
+
+	Periods map[int64]int64 // Number of periodic orbits by period.
+
+	Short      int64   // Orbits rejected by the threshold.
+	Registered int64   // Orbits registered to the histograms.
+	Lengths    []int64 // Number of registered orbits by length, where bucket n holds lengths in [2^(n-1), 2^n).
+	Inside     int64   // Pixels registered inside the image by the orbits.
+	Outside    int64   // Points of registered orbits outside the image. Orbits registered as paths don't count them.
+	Nearby     int64   // Long orbits found by searching nearby points of long orbits.
+
+	Elapsed    float64            // Seconds the render has taken, over all of its runs.
+	CPUSeconds map[string]float64 // Seconds spent in each phase of the render, summed over the workers, which exceeds the elapsed time of the phases the workers run in parallel.
+}
+
+// attempt adds the outcome of an orbit attempt which registered pixels points
+// inside the image.
+func (s *Stats) attempt(orbit *fractal.Orbit, frac *fractal.Fractal, pixels int64) {
+	s.Tries++
+	switch orbit.Outcome {
+	case fractal.InBulb:
+		s.InBulb++
+	case fractal.Cycle:
+		s.Cycles++
+		if s.Periods == nil {
+			s.Periods = make(map[int64]int64)
+		}
+		s.Periods[orbit.Period]++
+	case fractal.Escaped:
+		s.Escaped++
+	case fractal.Converged:
+		s.Converged++
+	case fractal.Untrapped:
+		s.Untrapped++
+	case fractal.Root:
+		s.Roots++
+	}
+	if orbit.Length < 0 {
+		return
+	}
+	if orbit.Length < frac.Threshold {
+		s.Short++
+		return
+	}
+	s.Registered++
+	s.Lengths = count(s.Lengths, orbit.Length)
+	s.Inside += pixels
+	s.Outside += orbit.Outside
+}
+
+// Add adds the statistics of o.
+func (s *Stats) Add(o *Stats) {
+	s.Tries += o.Tries
+	s.InBulb += o.InBulb
+	s.Cycles += o.Cycles
+	s.Escaped += o.Escaped
+	s.Converged += o.Converged
+	s.Untrapped += o.Untrapped
+	s.Roots += o.Roots
+	s.Short += o.Short
+	s.Registered += o.Registered
+	for period, n := range o.Periods {
+		if s.Periods == nil {
+			s.Periods = make(map[int64]int64)
+		}
+		s.Periods[period] += n
+	}
+	s.Lengths = merge(s.Lengths, o.Lengths)
+	s.Inside += o.Inside
+	s.Outside += o.Outside
+	s.Nearby += o.Nearby
+	s.Elapsed += o.Elapsed
+	for phase, secs := range o.CPUSeconds {
+		if s.CPUSeconds == nil {
+			s.CPUSeconds = make(map[string]float64)
+		}
+		s.CPUSeconds[phase] += secs
+	}
+}
+
+// count increases the bucket of v, where bucket n holds the values in
+// [2^(n-1), 2^n).
+func count(buckets []int64, v int64) []int64 {
+	n := bits.Len64(uint64(v))
+	for len(buckets) <= n {
+		buckets = append(buckets, 0)
+	}
+	buckets[n]++
+	return buckets
+}
+
+// merge adds the buckets of b to a.
+func merge(a, b []int64) []int64 {
+	for len(a) < len(b) {
+		a = append(a, 0)
+	}
+	for n, v := range b {
+		a[n] += v
+	}
+	return a
+}
+
+// Time adds the duration d, which a worker or the render spent in a phase of
+// the render, to the CPU seconds of the phase.
+func (s *Stats) Time(phase string, d time.Duration) {
+	if s.CPUSeconds == nil {
+		s.CPUSeconds = make(map[string]float64)
+	}
+	s.CPUSeconds[phase] += d.Seconds()
+}
+
+// Explain returns a summary of why the orbit attempts didn't register any
+// points inside the image.
+func (s *Stats) Explain() string {
+	if s.Tries == 0 {
+		return "no orbits were attempted"
+	}
+	var reasons []string
+	add := func(n int64, reason string) {
+		if n > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d %s", n, reason))
+		}
+	}
+	add(s.InBulb, "were inside the bulbs")
+	add(s.Cycles, "were cycles")
+	add(s.Escaped, "escaped")
+	add(s.Converged, "converged")
+	add(s.Untrapped, "missed the orbit trap")
+	add(s.Roots, "converged to a root")
+	add(s.Short, "were shorter than the threshold")
+	add(s.Registered, "were registered")
+	add(s.Outside, "registered points fell outside the image")
+	return fmt.Sprintf("of %d orbit attempts: %s", s.Tries, strings.Join(reasons, ", "))
+}
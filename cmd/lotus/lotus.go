@@ -1,17 +1,22 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
 	"image/jpeg"
 	_ "image/png"
 	"math"
 	"os"
+	"strconv"
 	"sync"
 
+	"github.com/karlek/wasabi/coloring"
+	"github.com/karlek/wasabi/formula"
 	"github.com/karlek/wasabi/fractal"
 	"github.com/karlek/wasabi/iro"
 	"github.com/karlek/wasabi/mandel"
+	"github.com/karlek/wasabi/perturb"
 	"github.com/pkg/profile"
 	"github.com/sirupsen/logrus"
 )
@@ -34,16 +39,201 @@ func abs(z complex128) float64 {
 	return real(z)*real(z) + imag(z)*imag(z)
 }
 
-var white = iro.RGBA{0xff, 0xff, 0xff, 0xff}
+// boundary is the thickness in pixels of the boundary drawn by the boundary
+// coloring.
+const boundary = 2
+
+// scalar returns the position in the gradient of an escaped pixel by the
+// coloring of the flags, from the number of iterations until it escaped, the
+// first point outside the bailout and its derivative. It returns false if the
+// pixel is drawn as the inside of the fractal.
+func scalar(escapesIn int64, last, der complex128) (float64, bool) {
+	// Distance to the fractal in pixels.
+	pixels := func() float64 {
+		return mandel.Distance(last, der) / (4 / zoom / width)
+	}
+	switch colorMode {
+	case "distance":
+		return math.Min(1, math.Log2(1+pixels())/10), true
+	case "filament":
+		// Filaments thinner than a pixel are drawn, even though they're
+		// missed by the centers of the pixels.
+		if pixels() < 0.5 {
+			return 0, false
+		}
+	case "boundary":
+		d := pixels()
+		if d >= boundary {
+			return 0, false
+		}
+		return 1 - d/boundary, true
+	case "fieldlines":
+		// Bands of the iteration where the orbits crossed a field line,
+		// between the external rays of the orbits which never did.
+		return float64(escapesIn) / float64(iterations), true
+	}
+	return smooth(float64(escapesIn), float64(iterations), last), true
+}
+
+// derivative returns true if the coloring needs the derivatives of the orbits.
+func derivative() bool {
+	return colorMode != "smooth" && colorMode != "fieldlines"
+}
+
+// white is the base color of the gradient. The channels of iro.RGBA range from
+// zero to one.
+var white = iro.RGBA{R: 1, G: 1, B: 1, A: 1}
 
 const (
-	width      = 1024
-	height     = 1024
-	iterations = 15
+	width  = 1024
+	height = 1024
 )
 
+var (
+	// Center of the render as decimal numbers, which keep their digits for
+	// deep zooms.
+	re, im string
+	// Zoom factor.
+	zoom float64
+	// Number of iterations.
+	iterations int64
+	// Render with perturbation theory.
+	perturbation bool
+	// Coloring of the escaped pixels.
+	colorMode string
+	// Squared bailout radius.
+	bailout float64
+	// Growth of the points of the orbits which crosses a field line.
+	growth float64
+	// Path to orbit trap image, and its width in the complex plane.
+	trapPath string
+	trapSize float64
+	// Formula whose roots are found by Newton's method.
+	newton string
+)
+
+func init() {
+	flag.StringVar(&re, "re", "0", "real part of the center")
+	flag.StringVar(&im, "im", "0", "imaginary part of the center")
+	flag.Float64Var(&zoom, "zoom", 1, "zoom factor")
+	flag.Int64Var(&iterations, "iterations", 15, "number of iterations")
+	flag.BoolVar(&perturbation, "perturb", false, "render with perturbation theory, which allows zooms up to about 1e300")
+	flag.StringVar(&colorMode, "color", "smooth", "coloring of the escaped pixels: smooth, distance (the estimated distance to the fractal), filament (smooth with filaments thinner than a pixel), boundary (only the boundary) or fieldlines (the external rays and field lines, where the orbits first grow more than -growth times)")
+	flag.Float64Var(&growth, "growth", mandel.FieldLinesGrowth, "growth of both parts of a point from the previous point which crosses a field line, used by the fieldlines coloring")
+	flag.Float64Var(&bailout, "bail", 0, "squared bailout radius, defaults to 4 for smooth coloring and 1e6 for the distance estimates")
+	flag.StringVar(&trapPath, "trap", "", "orbit trap path to image, which colors each pixel by the image at the first point of its orbit inside it")
+	flag.Float64Var(&trapSize, "trapsize", 1, "width of the orbit trap image in the complex plane, centered at origo")
+	flag.StringVar(&newton, "newton", "", "render the basins of the roots of a formula, e.g. \"z^3 - 1\", with Newton's method, colored by root and convergence speed")
+}
+
+// delta returns the distance from the center of the render to the pixel (x,
+// y).
+func delta(x, y int) complex128 {
+	return complex(2/zoom*(2*float64(x)/width-1), 2/zoom*(2*float64(y)/height-1))
+}
+
+// parseCenter parses the center of the render in double precision.
+func parseCenter() complex128 {
+	cr, err := strconv.ParseFloat(re, 64)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	ci, err := strconv.ParseFloat(im, 64)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	return complex(cr, ci)
+}
+
+// trapped returns the color of the texture at the first point of the orbit of
+// c which falls inside it, or false if the orbit escapes or ends before.
+func trapped(c complex128, frac *fractal.Fractal, texture *coloring.Texture) (iro.RGBA, bool) {
+	var z complex128
+	step := frac.Step()
+	for i := int64(0); i < frac.Iterations; i++ {
+		z = step(z, c, frac.Coef)
+		if col, ok := texture.At(z); ok {
+			return col, true
+		}
+		if mandel.IsOutside(z, frac.Bailout) {
+			break
+		}
+	}
+	return iro.RGBA{}, false
+}
+
+// basin returns the color of the pixel which starts Newton's method at z, by
+// the root it converges to and how fast.
+func basin(z complex128, frac *fractal.Fractal, roots []complex128) (iro.Color, bool) {
+	root, i := mandel.NewtonRoot(z, 0, frac)
+	if i == -1 {
+		return nil, false
+	}
+	k := mandel.Basin(root, roots)
+	if k == -1 {
+		return nil, false
+	}
+	shade := math.Max(0.1, 1-float64(i)/float64(frac.Iterations))
+	return iro.HSV{H: float64(k) / float64(len(roots)), S: 0.8, V: shade, A: 1}, true
+}
+
+// escaper returns a function which returns the first point outside the
+// bailout, its derivative if the coloring needs it, and the number of
+// iterations until it escaped, or -1, for the pixel at the distance dc from
+// the center.
+func escaper(frac *fractal.Fractal) func(dc complex128) (complex128, complex128, int64) {
+	if perturbation {
+		cr, ci, err := perturb.ParseCenter(re, im, zoom)
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		ref := perturb.NewReference(cr, ci, iterations, frac.Bailout)
+		if derivative() {
+			return func(dc complex128) (complex128, complex128, int64) {
+				return ref.EscapeDerivative(dc, iterations, frac.Bailout)
+			}
+		}
+		return func(dc complex128) (complex128, complex128, int64) {
+			last, escapesIn := ref.Escape(dc, iterations, frac.Bailout)
+			return last, 0, escapesIn
+		}
+	}
+	z, center := complex(0, 0), parseCenter()
+	if colorMode == "fieldlines" {
+		return func(dc complex128) (complex128, complex128, int64) {
+			last, escapesIn := mandel.FieldLinesEscapes(z, center+dc, frac, growth)
+			return last, 0, escapesIn
+		}
+	}
+	if derivative() {
+		return func(dc complex128) (complex128, complex128, int64) {
+			return mandel.EscapedDerivative(z, center+dc, frac)
+		}
+	}
+	return func(dc complex128) (complex128, complex128, int64) {
+		last, escapesIn := mandel.EscapedLast(z, center+dc, frac)
+		return last, 0, escapesIn
+	}
+}
+
 func main() {
 	defer profile.Start().Stop()
+	flag.Parse()
+	switch colorMode {
+	case "smooth", "distance", "filament", "boundary":
+	case "fieldlines":
+		if perturbation {
+			logrus.Fatalln("the field lines can't be rendered with perturbation theory")
+		}
+	default:
+		logrus.Fatalln("invalid coloring:", colorMode)
+	}
+	if bailout == 0 {
+		bailout = 4
+		if derivative() {
+			bailout = 1e6
+		}
+	}
 
 	ranges := []float64{}
 	for i := range iro.Viridis {
@@ -51,7 +241,6 @@ func main() {
 	}
 	gradient := iro.NewGradient(iro.Viridis, ranges, white, 256)
 
-	z := complex(0.0, 0.0)
 	// c := complex(0.285, 0.001)
 
 	// maxDist := -1.0
@@ -65,8 +254,10 @@ func main() {
 		height,
 		iterations,
 		nil,
-		complex(0, 0),
-		4e0,
+		// The coefficient of the mandelbrot, which the perturbation theory
+		// and the distance estimates assume.
+		complex(1, 0),
+		bailout,
 		fractal.Crci,
 		nil,
 		1,
@@ -81,7 +272,8 @@ func main() {
 		nil, nil,
 		0)
 
-	frac.Func = mandel.Test
+	// The renders without flags are of the mandelbrot too.
+	frac.Func, frac.FuncDC = mandel.Mandelbrot, mandel.MandelbrotDC
 	// frac.Func = func(z, c, _ complex128) complex128 {
 	// 	// return z*z + c
 	// 	// Burning-ship
@@ -126,13 +318,54 @@ func main() {
 	// wg.Add(width)
 	// fmt.Println(max)
 
+	var texture *coloring.Texture
+	if trapPath != "" {
+		if perturbation {
+			logrus.Fatalln("the orbit trap image can't be rendered with perturbation theory")
+		}
+		var err error
+		texture, err = coloring.LoadTexture(trapPath, 0, trapSize)
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+	}
+	center := parseCenter()
+
+	var roots []complex128
+	if newton != "" {
+		if perturbation || texture != nil {
+			logrus.Fatalln("Newton's method can't be rendered with perturbation theory or orbit traps")
+		}
+		expr, err := formula.Parse(newton)
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		d, err := expr.Derivative("z")
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		frac.Func = mandel.NewtonStep(expr.Func(), d.Func())
+		roots = mandel.NewtonRoots(0, frac)
+		logrus.Infof("roots of %s: %v", newton, roots)
+	}
+
+	escape := escaper(frac)
 	for j := 0; j < height; j++ {
 		go func(j int, frac *fractal.Fractal, img *image.RGBA, wg *sync.WaitGroup) {
 			for i := 0; i < width; i++ {
-				c := frac.ImageToComplex(i, j)
-
-				// last, escapesIn := mandel.FieldLinesEscapes(z, c, frac, 1e+1)
-				last, escapesIn := mandel.EscapedLast(z, c, frac)
+				if roots != nil {
+					if col, ok := basin(center+delta(i, j), frac, roots); ok {
+						img.SetRGBA(i, j, col.StandardRGBA())
+					}
+					continue
+				}
+				if texture != nil {
+					if col, ok := trapped(center+delta(i, j), frac, texture); ok {
+						img.SetRGBA(i, j, col.StandardRGBA())
+					}
+					continue
+				}
+				last, der, escapesIn := escape(delta(i, j))
 				// _, closest := mandel.OrbitTrap(z, c, frac, mandel.Pickover(complex(-0.5, 0.0)))
 				// last = closest
 				if escapesIn == -1 {
@@ -148,7 +381,10 @@ func main() {
 				// fmt.Println(scalar)
 				// scalar := plot.Value(plot.Exp, dist, max, 0.1, 2) / 255.
 				// fmt.Println(scalar)
-				scalar := smooth(float64(escapesIn), float64(frac.Iterations), last)
+				scalar, ok := scalar(escapesIn, last, der)
+				if !ok {
+					continue
+				}
 
 				// scalar := dist / max
 				col := gradient.Lookup(scalar)
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/karlek/wasabi/blueprint"
+	"github.com/karlek/wasabi/buddha"
+)
+
+// animate renders a julia set for each frame of the julia path of the
+// blueprint, saved as <out>-0000 and so on. Each frame is rendered from a copy
+// of the blueprint with its own parameter c, so its checkpoints resume the
+// frame.
+func animate(ctx context.Context, blue *blueprint.Blueprint) (err error) {
+	base := out
+	defer func() { out = base }()
+
+	cs := blue.JuliaFrames()
+	for i, c := range cs {
+		frame := *blue
+		frame.JuliaC = &blueprint.Complex{Real: real(c), Imag: imag(c)}
+		frame.JuliaPath, frame.Frames = nil, 0
+		out = fmt.Sprintf("%s-%04d", base, i)
+
+		logrus.Infof("[.] Rendering frame %d/%d of the julia set %v.", i+1, len(cs), c)
+		frac, ren := frame.Fractal(), frame.Render()
+		draw.Draw(ren.Image, ren.Image.Bounds(), &image.Uniform{frame.BaseColor.StandardRGBA()}, image.ZP, draw.Src)
+		readFlags(frac, ren)
+
+		interrupted := fill(ctx, frac, ren, &frame, buddha.NewCheckpoint())
+		if interrupted != nil && !errors.Is(interrupted, context.Canceled) {
+			return interrupted
+		}
+		if err := output(frac, ren, &frame); err != nil {
+			return err
+		}
+		if interrupted != nil {
+			return interrupted
+		}
+	}
+	return nil
+}
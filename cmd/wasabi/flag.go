@@ -11,6 +11,7 @@ import (
 	"github.com/karlek/wasabi/iro"
 	"github.com/karlek/wasabi/mandel"
 	"github.com/karlek/wasabi/plot"
+	"github.com/karlek/wasabi/progress"
 	"github.com/sirupsen/logrus"
 )
 
@@ -108,6 +109,9 @@ var (
 	// Silent flag
 	silent bool
 
+	// Progress reporting: bar, json or none.
+	progressStr string
+
 	// Interactive rendering
 	interactive bool
 
@@ -138,8 +142,9 @@ func init() {
 	flag.StringVar(&fun, "function", "exp", "color scaling function")
 	flag.StringVar(&modeStr, "mode", "iteration", "coloring mode")
 	flag.StringVar(&out, "out", "a", "output filename. Image file type will be suffixed.")
-	flag.StringVar(&palettePath, "palette", "", "path to image to be used as color palette")
-	flag.StringVar(&trapPath, "trap", "", "orbit trap path to image.")
+	flag.StringVar(&progressStr, "progress", "bar", "progress reporting: bar, json (lines on stderr) or none.")
+	flag.StringVar(&palettePath, "palette", "", "path to image to be used as color palette, whose -colors representative colors replace the gradient of the blueprint.")
+	flag.StringVar(&trapPath, "trap", "", "orbit trap path to image, which colors the orbits by the image at their points. Replaces the coloring of the blueprint.")
 	flag.Float64Var(&tries, "tries", 1e0, "number (width*height) of orbits attempts")
 	flag.Float64Var(&theta, "theta", 0, "rotation angle in radian")
 	flag.Float64Var(&realCoefficient, "realco", 1, "real coefficient for the complex function.")
@@ -163,6 +168,7 @@ func init() {
 // usage prints usage and flags for the program.
 func usage() {
 	fmt.Fprintf(os.Stderr, "%s [OPTIONS],,,\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "%s [OPTIONS] resume CHECKPOINT\n", os.Args[0])
 	flag.PrintDefaults()
 }
 
@@ -182,6 +188,24 @@ func parseModeFlag() {
 	}
 }
 
+// parseProgressFlag parses the _progress_ string to a progress reporter.
+func parseProgressFlag() progress.Reporter {
+	if silent {
+		return progress.Silent{}
+	}
+	switch progressStr {
+	case "bar":
+		return progress.NewTerminal(os.Stderr)
+	case "json":
+		return progress.NewJSON(os.Stderr)
+	case "none":
+		return progress.Silent{}
+	default:
+		logrus.Fatalln("invalid progress reporter:", progressStr)
+	}
+	return progress.Silent{}
+}
+
 // parseFunctionFlag parses the _fun_ string to a color scaling function.
 func parseFunctionFlag() {
 	switch fun {
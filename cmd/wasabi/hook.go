@@ -0,0 +1,90 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/karlek/wasabi/blueprint"
+	"github.com/karlek/wasabi/buddha"
+	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/plot"
+	"github.com/karlek/wasabi/render"
+)
+
+// hook is called by the render as chunks of orbit attempts complete, when the
+// histograms are consistent with the state of the render.
+type hook func(state *buddha.Checkpoint) error
+
+// hooks returns the hooks of the blueprint combined into one. It returns nil if
+// the blueprint has none.
+func hooks(frac *fractal.Fractal, ren *render.Render, blue *blueprint.Blueprint) hook {
+	var hs []hook
+	if blue.CheckpointInterval > 0 {
+		hs = append(hs, every(blue.CheckpointInterval, func(state *buddha.Checkpoint) error {
+			logrus.Infoln("[i] Saving checkpoint.")
+			return saveCheckpoint(out+"-checkpoint.gob", blue, frac, state)
+		}))
+	}
+	if blue.PreviewInterval > 0 {
+		hs = append(hs, every(blue.PreviewInterval, preview(frac, ren, blue)))
+	}
+	if blue.PreviewTries > 0 {
+		total := frac.Tries * float64(frac.Width*frac.Height)
+		hs = append(hs, each(blue.PreviewTries*total, preview(frac, ren, blue)))
+	}
+	if len(hs) == 0 {
+		return nil
+	}
+	return func(state *buddha.Checkpoint) error {
+		for _, h := range hs {
+			if err := h(state); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// every returns a hook which calls h if at least the number of seconds have
+// passed since the last call.
+func every(seconds float64, h hook) hook {
+	interval := time.Duration(seconds * float64(time.Second))
+	last := time.Now()
+	return func(state *buddha.Checkpoint) error {
+		if time.Since(last) < interval {
+			return nil
+		}
+		last = time.Now()
+		return h(state)
+	}
+}
+
+// each returns a hook which calls h each time the render has completed another
+// number of orbit attempts.
+func each(tries float64, h hook) hook {
+	next := tries
+	return func(state *buddha.Checkpoint) error {
+		if float64(state.Tries) < next {
+			return nil
+		}
+		for next <= float64(state.Tries) {
+			next += tries
+		}
+		return h(state)
+	}
+}
+
+// preview returns a hook which plots the histograms of the fractal and
+// overwrites the preview image.
+func preview(frac *fractal.Fractal, ren *render.Render, blue *blueprint.Blueprint) hook {
+	return func(state *buddha.Checkpoint) error {
+		logrus.Infoln("[i] Saving preview.")
+		pre := render.New(frac.Width, frac.Height, ren.F, ren.Factor, ren.Exposure)
+		draw.Draw(pre.Image, pre.Image.Bounds(), &image.Uniform{blue.BaseColor.StandardRGBA()}, image.ZP, draw.Src)
+		plot.Plot(pre, frac)
+		return pre.Render(blue.Png, blue.Jpg, out+"-preview")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/draw"
@@ -17,7 +18,7 @@ import (
 )
 
 func makeFrame(ren *render.Render, frac *fractal.Fractal) *pixel.PictureData {
-	ren.OrbitRatio = buddha.FillHistograms(frac, runtime.NumCPU())
+	ren.OrbitRatio, _, _ = buddha.FillHistograms(context.Background(), frac, runtime.NumCPU())
 	plot.Plot(ren, frac)
 	fmt.Println(frac.Theta)
 	fmt.Println(frac.Theta2)
@@ -44,7 +45,7 @@ func renderRun() {
 	ren, frac = blue.Render(), blue.Fractal()
 	draw.Draw(ren.Image, ren.Image.Bounds(), &image.Uniform{blue.BaseColor.StandardRGBA()}, image.ZP, draw.Src)
 
-	ren.OrbitRatio = buddha.FillHistograms(frac, runtime.NumCPU())
+	ren.OrbitRatio, _, _ = buddha.FillHistograms(context.Background(), frac, runtime.NumCPU())
 	ren.Exposure = exposure
 	ren.Factor = factor
 	ren.F = f
@@ -2,10 +2,15 @@ package main
 
 import (
 	"encoding/gob"
+	"encoding/json"
 	"image"
+	"io/ioutil"
 	"os"
 
+	"github.com/karlek/wasabi/blueprint"
+	"github.com/karlek/wasabi/buddha"
 	"github.com/karlek/wasabi/fractal"
+	"github.com/karlek/wasabi/histo"
 	"github.com/karlek/wasabi/iro"
 	colorful "github.com/lucasb-eyer/go-colorful"
 )
@@ -47,3 +52,65 @@ func loadHistogram(filename string) (frac *fractal.Fractal, err error) {
 func loadArt() (frac *fractal.Fractal, err error) {
 	return loadHistogram("r-g-b.gob")
 }
+
+// checkpoint is the saved state of an unfinished render.
+type checkpoint struct {
+	Blueprint *blueprint.Blueprint
+	State     *buddha.Checkpoint
+
+	R, G, B, Importance histo.Histo
+}
+
+// saveCheckpoint saves the state of the render. The checkpoint is written to a
+// temporary file first, so that an interrupted save keeps the previous one.
+func saveCheckpoint(filename string, blue *blueprint.Blueprint, frac *fractal.Fractal, state *buddha.Checkpoint) (err error) {
+	tmp := filename + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	enc := gob.NewEncoder(file)
+	err = enc.Encode(checkpoint{
+		Blueprint:  blue,
+		State:      state,
+		R:          frac.R,
+		G:          frac.G,
+		B:          frac.B,
+		Importance: frac.Importance,
+	})
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// loadCheckpoint loads the state of a render and recreates its fractal.
+func loadCheckpoint(filename string) (blue *blueprint.Blueprint, frac *fractal.Fractal, state *buddha.Checkpoint, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer file.Close()
+	var cp checkpoint
+	dec := gob.NewDecoder(file)
+	if err := dec.Decode(&cp); err != nil {
+		return nil, nil, nil, err
+	}
+
+	frac = cp.Blueprint.Fractal()
+	frac.R, frac.G, frac.B, frac.Importance = cp.R, cp.G, cp.B, cp.Importance
+	return cp.Blueprint, frac, cp.State, nil
+}
+
+// saveStats saves the statistics of a render as JSON.
+func saveStats(filename string, stats *buddha.Stats) (err error) {
+	buf, err := json.MarshalIndent(stats, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(buf, '\n'), 0644)
+}
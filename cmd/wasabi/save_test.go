@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/karlek/wasabi/blueprint"
+	"github.com/karlek/wasabi/buddha"
+	"github.com/karlek/wasabi/histo"
+	"github.com/karlek/wasabi/iro"
+)
+
+// testBlueprint returns a small blueprint of four chunks of orbit attempts.
+func testBlueprint() *blueprint.Blueprint {
+	return &blueprint.Blueprint{
+		Iterations:      100,
+		Tries:           4 * (1 << 16) / (32 * 32),
+		Coloring:        "iteration",
+		Width:           32,
+		Height:          32,
+		Zoom:            1,
+		RealCoefficient: 1,
+		Seed:            1,
+		Function:        "exp",
+		RegisterMode:    "escapes",
+		ComplexFunction: "mandelbrot",
+		Plane:           "zrzi",
+		Gradient:        []iro.RGBA{{R: 1, G: 0.5, B: 0.25, A: 1}, {R: 0.25, G: 0.5, B: 1, A: 1}},
+		Range:           []float64{0, 1},
+		ZUpdate:         "origo",
+		CUpdate:         "random",
+		Lanes:           2,
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	phoenix := testBlueprint()
+	phoenix.Sampler = "metropolis"
+	phoenix.ComplexFunction = "phoenix"
+	phoenix.PhoenixP = &blueprint.Complex{Real: -0.5, Imag: 0.1}
+	phoenix.JuliaC = &blueprint.Complex{Real: -0.4, Imag: 0.6}
+	phoenix.ZUpdate = ""
+
+	exact := testBlueprint()
+	exact.Sampler = "importance"
+	exact.Precision = "dd"
+	exact.RealExact, exact.ImagExact = "-0.75", "0.1"
+
+	dir, err := ioutil.TempDir("", "wasabi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, blue := range []*blueprint.Blueprint{phoenix, exact} {
+		want := blue.Fractal()
+		if _, _, err := buddha.FillHistograms(context.Background(), want, 2); err != nil {
+			t.Fatalf("%s: %v", blue.Sampler, err)
+		}
+
+		// Interrupt the render in its last pass, and save it once it has
+		// stopped.
+		ctx, cancel := context.WithCancel(context.Background())
+		frac, state := blue.Fractal(), buddha.NewCheckpoint()
+		save := func(state *buddha.Checkpoint) error {
+			if state.Tries >= 1<<16 {
+				cancel()
+			}
+			return nil
+		}
+		if _, _, err := buddha.Resume(ctx, frac, state, 2, nil, save); err != context.Canceled {
+			t.Fatalf("%s: interrupted render returned %v", blue.Sampler, err)
+		}
+		fname := filepath.Join(dir, blue.Sampler+"-checkpoint.gob")
+		if err := saveCheckpoint(fname, blue, frac, state); err != nil {
+			t.Fatal(err)
+		}
+
+		loadedBlue, got, loaded, err := loadCheckpoint(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loadedBlue, blue) {
+			t.Errorf("%d: loaded blueprint %+v, want %+v", i, loadedBlue, blue)
+		}
+		if !reflect.DeepEqual(loaded.Grid, state.Grid) || !reflect.DeepEqual(loaded.Seeds, state.Seeds) {
+			t.Errorf("%s: the state of the sampler changed when it was loaded", blue.Sampler)
+		}
+		if _, _, err := buddha.Resume(context.Background(), got, loaded, 4, nil, nil); err != nil {
+			t.Fatalf("%s: %v", blue.Sampler, err)
+		}
+		for _, h := range []struct {
+			channel   string
+			got, want histo.Histo
+		}{{"red", got.R, want.R}, {"green", got.G, want.G}, {"blue", got.B, want.B}} {
+			if !reflect.DeepEqual(h.got, h.want) {
+				t.Errorf("%s: the %s histogram of the resumed render differs", blue.Sampler, h.channel)
+			}
+		}
+		if histo.Max(want.R) == 0 {
+			t.Errorf("%s: the render is black", blue.Sampler)
+		}
+		if got.Streams != want.Streams {
+			t.Errorf("%s: resumed render sampled %d streams, want %d", blue.Sampler, got.Streams, want.Streams)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"image"
@@ -24,10 +26,12 @@ import (
 )
 
 func main() {
-	defer profile.Start(profile.CPUProfile).Stop()
+	// The interrupts are handled by the render, which saves its state.
+	defer profile.Start(profile.CPUProfile, profile.NoShutdownHook).Stop()
 
-	// Handle interrupts as fails, so we can chain with an image viewer.
-	handleInterrupts()
+	// Handle interrupts by stopping the render, and later fail so we can chain
+	// with an image viewer.
+	ctx := handleInterrupts()
 
 	// Parse flag and demand blueprint file.
 	handleFlags()
@@ -41,13 +45,19 @@ func main() {
 	case mergeFlag:
 		// Merge histograms.
 		err = merge(flag.Args())
+	case flag.Arg(0) == "resume":
+		// Resume render from checkpoint.
+		err = resumeBuddha(ctx, flag.Arg(1))
 	default:
 		// Render blueprint.
-		err = renderBuddha(flag.Arg(0))
+		err = renderBuddha(ctx, flag.Arg(0))
 	}
 	if err != nil {
 		logrus.Warnln(err)
 	}
+	if errors.Is(err, context.Canceled) {
+		os.Exit(1)
+	}
 }
 
 // Parse flag and demand blueprint file.
@@ -64,14 +74,20 @@ func handleFlags() {
 	}
 }
 
-// Handle interrupts as fails, so we can chain with an image viewer.
-func handleInterrupts() {
+// Handle interrupts by cancelling the returned context, which stops the render
+// and keeps the orbits found so far. A second interrupt exits immediately.
+func handleInterrupts() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
 	inter := make(chan os.Signal, 1)
 	signal.Notify(inter, os.Interrupt)
 	go func(inter chan os.Signal) {
+		<-inter
+		logrus.Warnln("[!] Interrupted, stopping the render. Interrupt again to exit.")
+		cancel()
 		<-inter
 		os.Exit(1)
 	}(inter)
+	return ctx
 }
 
 func initialize(blueprintPath string) (frac *fractal.Fractal, ren *render.Render, blue *blueprint.Blueprint, err error) {
@@ -79,6 +95,15 @@ func initialize(blueprintPath string) (frac *fractal.Fractal, ren *render.Render
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	// The orbit trap image of the flags replaces the coloring of the
+	// blueprint.
+	if trapPath != "" {
+		blue.Coloring, blue.Texture = "texture", trapPath
+	}
+	// The palette image of the flags replaces the gradient of the blueprint.
+	if palettePath != "" {
+		blue.PaletteImage, blue.PaletteColors = palettePath, colors
+	}
 	frac, ren = blue.Fractal(), blue.Render()
 	draw.Draw(ren.Image, ren.Image.Bounds(), &image.Uniform{blue.BaseColor.StandardRGBA()}, image.ZP, draw.Src)
 	return frac, ren, blue, nil
@@ -93,7 +118,7 @@ func readFlags(frac *fractal.Fractal, ren *render.Render) {
 	}
 }
 
-func renderBuddha(blueprintPath string) (err error) {
+func renderBuddha(ctx context.Context, blueprintPath string) (err error) {
 	logrus.Infoln("[.] Initializing.")
 	frac, ren, blue, err := initialize(blueprintPath)
 	if err != nil {
@@ -101,6 +126,11 @@ func renderBuddha(blueprintPath string) (err error) {
 	}
 	readFlags(frac, ren)
 
+	if len(blue.JuliaPath) > 0 && !load {
+		return animate(ctx, blue)
+	}
+
+	var interrupted error
 	if load {
 		logrus.Infoln("[-] Loading visits.")
 		frac, err = loadArt()
@@ -108,18 +138,80 @@ func renderBuddha(blueprintPath string) (err error) {
 			return err
 		}
 	} else {
-		ren.OrbitRatio = buddha.FillHistograms(frac, runtime.NumCPU())
-		if histo.Max(frac.R)+histo.Max(frac.G)+histo.Max(frac.B) == 0 {
-			out += "-black"
-			return fmt.Errorf("black")
+		interrupted = fill(ctx, frac, ren, blue, buddha.NewCheckpoint())
+		if interrupted != nil && !errors.Is(interrupted, context.Canceled) {
+			return interrupted
 		}
-		if blue.CacheHistograms {
-			logrus.Infoln("[i] Saving r, g, b channels")
-			if err := saveArt(frac); err != nil {
+	}
+	if err := output(frac, ren, blue); err != nil {
+		return err
+	}
+	return interrupted
+}
+
+// resumeBuddha continues the render of a checkpoint.
+func resumeBuddha(ctx context.Context, checkpointPath string) (err error) {
+	logrus.Infoln("[-] Loading checkpoint.")
+	blue, frac, state, err := loadCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+	ren := blue.Render()
+	draw.Draw(ren.Image, ren.Image.Bounds(), &image.Uniform{blue.BaseColor.StandardRGBA()}, image.ZP, draw.Src)
+	readFlags(frac, ren)
+
+	logrus.Infof("[i] Resuming after %d orbit attempts.", state.Tries)
+	interrupted := fill(ctx, frac, ren, blue, state)
+	if interrupted != nil && !errors.Is(interrupted, context.Canceled) {
+		return interrupted
+	}
+	if err := output(frac, ren, blue); err != nil {
+		return err
+	}
+	return interrupted
+}
+
+// fill finds the orbits of the fractal from the state of the render, saves the
+// statistics of the render, and saves checkpoints, previews and caches the
+// histograms as chosen by the blueprint. If the render is interrupted the
+// partial histograms are kept and the error of the context is returned.
+func fill(ctx context.Context, frac *fractal.Fractal, ren *render.Render, blue *blueprint.Blueprint, state *buddha.Checkpoint) (err error) {
+	var tries int64
+	ren.OrbitRatio, tries, err = buddha.Resume(ctx, frac, state, runtime.NumCPU(), parseProgressFlag(), hooks(frac, ren, blue))
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	if err != nil {
+		// Keep the partial render, but report the interruption when we're
+		// done. The histograms are consistent with the state of the render,
+		// which is saved so the render can be continued.
+		logrus.Warnf("[!] Render stopped after %d orbit attempts.", tries)
+		frac.Tries = float64(tries) / float64(frac.Width*frac.Height)
+		if blue.CheckpointInterval > 0 {
+			logrus.Infoln("[i] Saving checkpoint.")
+			if err := saveCheckpoint(out+"-checkpoint.gob", blue, frac, state); err != nil {
 				return err
 			}
 		}
 	}
+	if err := saveStats(out+"-stats.json", &state.Stats); err != nil {
+		return err
+	}
+	if histo.Max(frac.R)+histo.Max(frac.G)+histo.Max(frac.B) == 0 {
+		out += "-black"
+		return fmt.Errorf("black, %s", state.Stats.Explain())
+	}
+	if blue.CacheHistograms {
+		logrus.Infoln("[i] Saving r, g, b channels")
+		if err := saveArt(frac); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// output plots the histograms of the fractal and saves the images.
+func output(frac *fractal.Fractal, ren *render.Render, blue *blueprint.Blueprint) (err error) {
 	logrus.Infoln("[i] Density", ren.OrbitRatio)
 	// div := (3 * 10 * ren.OrbitRatio * frac.Tries * float64((histo.Max(frac.R) + histo.Max(frac.G) + histo.Max(frac.B))))
 	// div := ren.OrbitRatio * frac.Tries
@@ -161,12 +253,17 @@ func merge(filenames []string) (err error) {
 	if err != nil {
 		return err
 	}
+	// The random streams of the merged histograms.
+	var merged []sampled
 	for i, fname := range filenames[:len(filenames)-1] {
 		fmt.Printf("\r[i] %d/%d", i+1, len(filenames)-1)
 		tmpFrac, err := loadHistogram(fname)
 		if err != nil {
 			return err
 		}
+		if merged, err = checkStreams(merged, fname, tmpFrac); err != nil {
+			return err
+		}
 		if frac.R, err = histo.Merge(tmpFrac.R, frac.R); err != nil {
 			return err
 		}
@@ -184,3 +281,29 @@ func merge(filenames []string) (err error) {
 	}
 	return nil
 }
+
+// sampled are the random streams sampled by a merged histogram.
+type sampled struct {
+	fname         string
+	seed, streams int64
+}
+
+// checkStreams refuses to merge a histogram which sampled any of the random
+// streams of the previous histograms, since the merged render would contain
+// the same orbits twice. Histograms which don't record their streams are only
+// refused if they have the same seed as a previous histogram. It returns the
+// streams of the previous histograms and the histogram.
+func checkStreams(prev []sampled, fname string, frac *fractal.Fractal) ([]sampled, error) {
+	if frac.Streams == 0 {
+		logrus.Warnf("[!] %s doesn't record its random streams, and may overlap with the other histograms.", fname)
+	}
+	for _, p := range prev {
+		if p.seed == frac.Seed && (p.streams == 0 || frac.Streams == 0) {
+			return nil, fmt.Errorf("%s and %s were rendered with the same seed %d, and contain the same orbits", p.fname, fname, frac.Seed)
+		}
+		if buddha.StreamsOverlap(p.seed, p.streams, frac.Seed, frac.Streams) {
+			return nil, fmt.Errorf("%s and %s sampled the same random streams, and contain the same orbits", p.fname, fname)
+		}
+	}
+	return append(prev, sampled{fname: fname, seed: frac.Seed, streams: frac.Streams}), nil
+}
@@ -4,6 +4,7 @@ package coloring
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"text/tabwriter"
 
 	"github.com/karlek/wasabi/iro"
@@ -11,8 +12,16 @@ import (
 
 // Coloring contains information on how to color a fractal.
 type Coloring struct {
-	Grad iro.Gradient
-	mode Mode
+	Grad  iro.Gradient
+	Bands []Band // Length bands of the Bands mode, colored by the gradient color of the same index.
+
+	Texture *Texture // Image of the ImageTrap mode.
+	mode    Mode
+}
+
+// Band is a range of orbit lengths, from Min up to but not including Max.
+type Band struct {
+	Min, Max int64
 }
 
 // Mode returns the coloring mode.
@@ -25,6 +34,12 @@ func (c *Coloring) String() string {
 	w := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', 0)
 	fmt.Fprintf(w, "\tGrad:\t%v\n", c.Grad)
 	fmt.Fprintf(w, "\tMode:\t%v\n", c.mode)
+	if c.mode == Bands {
+		fmt.Fprintf(w, "\tBands:\t%v\n", c.Bands)
+	}
+	if c.mode == ImageTrap {
+		fmt.Fprintf(w, "\tTexture:\t%dx%d at %v to %v\n", c.Texture.Width, c.Texture.Height, c.Texture.Min, c.Texture.Max)
+	}
 	w.Flush()
 	return string(buf.Bytes())
 }
@@ -48,6 +63,8 @@ func (c *Coloring) Get(i int64, it int64) (float64, float64, float64) {
 		return c.iteration(i, it)
 	case Path:
 		return c.vector(i, it)
+	case Bands:
+		return c.bands(i)
 	default:
 		return c.modulo(i)
 	}
@@ -81,6 +98,31 @@ func (c *Coloring) iteration(i int64, it int64) (float64, float64, float64) {
 	return (c.Grad.Colors)[key].RGB()
 }
 
+// bands returns the sum of the colors of the bands which the orbit length i
+// fits in.
+func (c *Coloring) bands(i int64) (red, green, blue float64) {
+	for k, b := range c.Bands {
+		if i < b.Min || i >= b.Max {
+			continue
+		}
+		r, g, b := c.Grad.Colors[k].RGB()
+		red, green, blue = red+r, green+g, blue+b
+	}
+	return red, green, blue
+}
+
+// Basin returns the color of the root with index root of the roots, evenly
+// spaced over the gradient, and darkened the longer the orbit took to converge
+// of the iterations it. Unknown roots, of index -1, have the base color.
+func (c *Coloring) Basin(root, roots int, i, it int64) (float64, float64, float64) {
+	if root < 0 || root >= roots {
+		return c.Grad.Base.RGB()
+	}
+	red, green, blue := c.Grad.Lookup((float64(root) + 0.5) / float64(roots)).RGB()
+	shade := math.Max(0.1, 1-float64(i)/float64(it))
+	return shade * red, shade * green, shade * blue
+}
+
 // orbit returns the gradient color.
 func (c *Coloring) orbit(i, it int64) (float64, float64, float64) {
 	return c.Grad.Lookup(float64(i) / float64(it)).RGB()
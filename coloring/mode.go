@@ -14,6 +14,15 @@ const (
 	VectorField
 	// Path linearly interpolates between the points in the path.
 	Path
+	// Bands adds each orbit with the colors of every length band it fits in,
+	// e.g. a nebulabrot.
+	Bands
+	// ImageTrap adds the colors of a texture at the points of the orbits which
+	// fall inside it.
+	ImageTrap
+	// Basin colors the orbits of Newton's method by the root they converged
+	// to, shaded by how fast they converged.
+	Basin
 )
 
 func (m Mode) String() string {
@@ -28,6 +37,12 @@ func (m Mode) String() string {
 		return "OrbitLength"
 	case Path:
 		return "Path"
+	case Bands:
+		return "Bands"
+	case ImageTrap:
+		return "ImageTrap"
+	case Basin:
+		return "Basin"
 	default:
 		return "fail"
 	}
@@ -0,0 +1,83 @@
+package coloring
+
+import (
+	"image"
+	// Decoders of the texture images.
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+
+	"github.com/karlek/wasabi/iro"
+)
+
+// Texture is an image mapped onto a rectangle of the complex plane, whose
+// colors are sampled by the points of the orbits which fall inside it.
+type Texture struct {
+	Width, Height int
+	Pix           []iro.RGBA // Colors of the pixels, row by row from the top.
+	Min, Max      complex128 // Corners of the rectangle with the smallest and largest parts.
+}
+
+// NewTexture maps the image onto the rectangle of the complex plane centered
+// at center, which is size wide and keeps the aspect ratio of the image.
+func NewTexture(img image.Image, center complex128, size float64) *Texture {
+	bounds := img.Bounds()
+	t := &Texture{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Pix:    make([]iro.RGBA, 0, bounds.Dx()*bounds.Dy()),
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a == 0 {
+				t.Pix = append(t.Pix, iro.RGBA{})
+				continue
+			}
+			// Undo the premultiplied alpha.
+			t.Pix = append(t.Pix, iro.RGBA{
+				R: float64(r) / float64(a),
+				G: float64(g) / float64(a),
+				B: float64(b) / float64(a),
+				A: float64(a) / 0xffff,
+			})
+		}
+	}
+	half := complex(size/2, size/2*float64(t.Height)/float64(t.Width))
+	t.Min, t.Max = center-half, center+half
+	return t
+}
+
+// LoadTexture decodes the image at path and maps it like NewTexture.
+func LoadTexture(path string, center complex128, size float64) (*Texture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return NewTexture(img, center, size), nil
+}
+
+// At returns the color of the texture at the point z, or false if the point is
+// outside of the texture or on a transparent pixel. The imaginary axis points
+// down the image, like in the renders.
+func (t *Texture) At(z complex128) (iro.RGBA, bool) {
+	if real(z) < real(t.Min) || real(z) >= real(t.Max) || imag(z) < imag(t.Min) || imag(z) >= imag(t.Max) {
+		return iro.RGBA{}, false
+	}
+	x := int((real(z) - real(t.Min)) / (real(t.Max) - real(t.Min)) * float64(t.Width))
+	y := int((imag(z) - imag(t.Min)) / (imag(t.Max) - imag(t.Min)) * float64(t.Height))
+	// Guard against rounding at the edges.
+	if x >= t.Width || y >= t.Height {
+		return iro.RGBA{}, false
+	}
+	col := t.Pix[y*t.Width+x]
+	if col.A == 0 {
+		return iro.RGBA{}, false
+	}
+	return col, true
+}
@@ -0,0 +1,64 @@
+package coloring
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/karlek/wasabi/iro"
+)
+
+// textureImage returns a 2×2 image of red and green above blue and a
+// transparent pixel.
+func textureImage() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 255, 0, 255})
+	img.Set(0, 1, color.NRGBA{0, 0, 255, 255})
+	img.Set(1, 1, color.NRGBA{255, 255, 255, 0})
+	return img
+}
+
+func TestTextureAt(t *testing.T) {
+	// The texture covers the square from -1-1i to 1+1i, with the imaginary
+	// axis pointing down the image.
+	tex := NewTexture(textureImage(), 0, 2)
+	red, green, blue := iro.RGBA{R: 1, A: 1}, iro.RGBA{G: 1, A: 1}, iro.RGBA{B: 1, A: 1}
+	golden := []struct {
+		z    complex128
+		want iro.RGBA
+		ok   bool
+	}{
+		{complex(-0.5, -0.5), red, true},
+		{complex(0.5, -0.5), green, true},
+		{complex(-0.5, 0.5), blue, true},
+		// Transparent pixel.
+		{complex(0.5, 0.5), iro.RGBA{}, false},
+		// The smallest corner is inside, and the largest outside.
+		{complex(-1, -1), red, true},
+		{complex(1, 0), iro.RGBA{}, false},
+		{complex(0, 1), iro.RGBA{}, false},
+		{complex(-1.5, 0), iro.RGBA{}, false},
+	}
+	for _, g := range golden {
+		got, ok := tex.At(g.z)
+		if got != g.want || ok != g.ok {
+			t.Errorf("%v: got %v, %v, want %v, %v", g.z, got, ok, g.want, g.ok)
+		}
+	}
+}
+
+func TestTextureAlpha(t *testing.T) {
+	// The colors of translucent pixels aren't darkened by their alpha.
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.NRGBA{255, 51, 0, 128})
+	got, ok := NewTexture(img, 0, 2).At(0)
+	if !ok {
+		t.Fatal("translucent pixel is outside the texture")
+	}
+	want := iro.RGBA{R: 1, G: 0.2, A: 128.0 / 255}
+	if math.Abs(got.R-want.R) > 1e-3 || math.Abs(got.G-want.G) > 1e-3 || got.B != 0 || math.Abs(got.A-want.A) > 1e-3 {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
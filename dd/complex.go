@@ -0,0 +1,53 @@
+package dd
+
+// Complex is a complex number of double-double parts.
+type Complex struct {
+	Re, Im Float
+}
+
+// Cmplx returns the double-double complex number of c.
+func Cmplx(c complex128) Complex {
+	return Complex{New(real(c)), New(imag(c))}
+}
+
+// Hi returns the high parts of the complex number.
+func (a Complex) Hi() complex128 {
+	return complex(a.Re.Hi, a.Im.Hi)
+}
+
+// Lo returns the low parts of the complex number.
+func (a Complex) Lo() complex128 {
+	return complex(a.Re.Lo, a.Im.Lo)
+}
+
+// Add returns a + b.
+func (a Complex) Add(b Complex) Complex {
+	return Complex{a.Re.Add(b.Re), a.Im.Add(b.Im)}
+}
+
+// Sub returns a - b.
+func (a Complex) Sub(b Complex) Complex {
+	return Complex{a.Re.Sub(b.Re), a.Im.Sub(b.Im)}
+}
+
+// Mul returns a * b.
+func (a Complex) Mul(b Complex) Complex {
+	return Complex{
+		a.Re.Mul(b.Re).Sub(a.Im.Mul(b.Im)),
+		a.Re.Mul(b.Im).Add(a.Im.Mul(b.Re)),
+	}
+}
+
+// Sqr returns a * a.
+func (a Complex) Sqr() Complex {
+	im := a.Re.Mul(a.Im)
+	return Complex{
+		a.Re.Sqr().Sub(a.Im.Sqr()),
+		im.Add(im),
+	}
+}
+
+// Abs2 returns the squared absolute value of a, rounded to a float64.
+func (a Complex) Abs2() float64 {
+	return a.Re.Sqr().Add(a.Im.Sqr()).Float64()
+}
@@ -0,0 +1,120 @@
+// Package dd implements double-double arithmetic, which represents a number as
+// the unevaluated sum of two float64 and gives about 32 significant digits. It
+// is considerably cheaper than math/big for moderately deep zooms.
+//
+// Credits: Hida, Li and Bailey, "Library for Double-Double and Quad-Double
+// Arithmetic", 2007.
+package dd
+
+import (
+	"math/big"
+)
+
+// Float is a double-double number Hi + Lo, where |Lo| <= ulp(Hi)/2.
+type Float struct {
+	Hi, Lo float64
+}
+
+// New returns the double-double number of f.
+func New(f float64) Float {
+	return Float{Hi: f}
+}
+
+// Float64 returns the number rounded to a float64.
+func (a Float) Float64() float64 {
+	return a.Hi + a.Lo
+}
+
+// twoSum returns the sum of a and b together with its rounding error.
+func twoSum(a, b float64) (s, e float64) {
+	s = a + b
+	bb := s - a
+	e = (a - (s - bb)) + (b - bb)
+	return s, e
+}
+
+// quickTwoSum is twoSum for |a| >= |b|.
+func quickTwoSum(a, b float64) (s, e float64) {
+	s = a + b
+	e = b - (s - a)
+	return s, e
+}
+
+// splitter is 2^27 + 1, used to split a float64 into two halves of 26 bits.
+const splitter = 134217729
+
+// split splits a into a high and low part which can be multiplied exactly.
+func split(a float64) (hi, lo float64) {
+	t := splitter * a
+	hi = t - (t - a)
+	lo = a - hi
+	return hi, lo
+}
+
+// twoProd returns the product of a and b together with its rounding error, by
+// Dekker's algorithm.
+func twoProd(a, b float64) (p, e float64) {
+	p = a * b
+	ah, al := split(a)
+	bh, bl := split(b)
+	e = ((ah*bh - p) + ah*bl + al*bh) + al*bl
+	return p, e
+}
+
+// Add returns a + b.
+func (a Float) Add(b Float) Float {
+	s, e := twoSum(a.Hi, b.Hi)
+	t, f := twoSum(a.Lo, b.Lo)
+	e += t
+	s, e = quickTwoSum(s, e)
+	e += f
+	s, e = quickTwoSum(s, e)
+	return Float{s, e}
+}
+
+// Neg returns -a.
+func (a Float) Neg() Float {
+	return Float{-a.Hi, -a.Lo}
+}
+
+// Sub returns a - b.
+func (a Float) Sub(b Float) Float {
+	return a.Add(b.Neg())
+}
+
+// Mul returns a * b.
+func (a Float) Mul(b Float) Float {
+	p, e := twoProd(a.Hi, b.Hi)
+	e += a.Hi*b.Lo + a.Lo*b.Hi
+	p, e = quickTwoSum(p, e)
+	return Float{p, e}
+}
+
+// Sqr returns a * a.
+func (a Float) Sqr() Float {
+	p, e := twoProd(a.Hi, a.Hi)
+	e += 2 * a.Hi * a.Lo
+	p, e = quickTwoSum(p, e)
+	return Float{p, e}
+}
+
+// Big returns the exact value of the number.
+func (a Float) Big() *big.Float {
+	x := new(big.Float).SetPrec(128).SetFloat64(a.Hi)
+	return x.Add(x, new(big.Float).SetFloat64(a.Lo))
+}
+
+// Parse parses a decimal number with up to about 32 significant digits.
+func Parse(s string) (Float, error) {
+	x, _, err := big.ParseFloat(s, 10, 128, big.ToNearestEven)
+	if err != nil {
+		return Float{}, err
+	}
+	hi, _ := x.Float64()
+	lo, _ := x.Sub(x, new(big.Float).SetFloat64(hi)).Float64()
+	return Float{hi, lo}, nil
+}
+
+func (a Float) String() string {
+	return a.Big().Text('g', 32)
+}
@@ -0,0 +1,73 @@
+package dd
+
+import (
+	"math/big"
+	"testing"
+)
+
+// tolerance is the largest relative error of the operations, about 2^-104.
+var tolerance = big.NewFloat(1e-31)
+
+// near returns true if the relative error of got is within the tolerance.
+func near(got Float, want *big.Float) bool {
+	diff := new(big.Float).SetPrec(256).Sub(got.Big(), want)
+	diff.Abs(diff)
+	bound := new(big.Float).SetPrec(256).Abs(want)
+	bound.Mul(bound, tolerance)
+	return diff.Cmp(bound) <= 0
+}
+
+func TestArithmetic(t *testing.T) {
+	nums := []string{
+		"1.2345678901234567890123456789012",
+		"-0.74364388703715870475219150611",
+		"3.1415926535897932384626433832795",
+		"1e-20",
+		"-7.0000000000000000000000000000001",
+	}
+	for _, s1 := range nums {
+		for _, s2 := range nums {
+			a, err := Parse(s1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(s2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			x, y := a.Big().SetPrec(256), b.Big().SetPrec(256)
+			golden := []struct {
+				op   string
+				got  Float
+				want *big.Float
+			}{
+				{"+", a.Add(b), new(big.Float).SetPrec(256).Add(x, y)},
+				{"-", a.Sub(b), new(big.Float).SetPrec(256).Sub(x, y)},
+				{"*", a.Mul(b), new(big.Float).SetPrec(256).Mul(x, y)},
+				{"^2", a.Sqr(), new(big.Float).SetPrec(256).Mul(x, x)},
+			}
+			for _, g := range golden {
+				if g.want.Sign() != 0 && !near(g.got, g.want) {
+					t.Errorf("%s %s %s: got %v, want %v", s1, g.op, s2, g.got, g.want.Text('g', 32))
+				}
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	a, err := Parse("0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Hi != 0.1 || a.Lo == 0 {
+		t.Errorf("0.1: got %v + %v, expected the float64 0.1 and a rounding error", a.Hi, a.Lo)
+	}
+	// The sum rounds to 0.1 with 32 digits, unlike the float64.
+	if got := a.String(); got != "0.1" {
+		t.Errorf("0.1: got %s", got)
+	}
+	if _, err := Parse("0.1x"); err == nil {
+		t.Errorf("0.1x: expected an error")
+	}
+}
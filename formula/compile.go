@@ -0,0 +1,181 @@
+package formula
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/karlek/wasabi/mandel"
+)
+
+// constants are the named constants of formulas.
+var constants = map[string]complex128{
+	"i":  1i,
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// funcs are the functions of formulas.
+var funcs = map[string]func(complex128) complex128{
+	"sin":   cmplx.Sin,
+	"cos":   cmplx.Cos,
+	"tan":   cmplx.Tan,
+	"cot":   cmplx.Cot,
+	"sinh":  cmplx.Sinh,
+	"cosh":  cmplx.Cosh,
+	"tanh":  cmplx.Tanh,
+	"asin":  cmplx.Asin,
+	"acos":  cmplx.Acos,
+	"atan":  cmplx.Atan,
+	"asinh": cmplx.Asinh,
+	"acosh": cmplx.Acosh,
+	"atanh": cmplx.Atanh,
+	"exp":   cmplx.Exp,
+	"log":   cmplx.Log,
+	"log10": cmplx.Log10,
+	"sqrt":  cmplx.Sqrt,
+	"conj":  cmplx.Conj,
+	"abs":   func(x complex128) complex128 { return complex(cmplx.Abs(x), 0) },
+	"real":  func(x complex128) complex128 { return complex(real(x), 0) },
+	"imag":  func(x complex128) complex128 { return complex(imag(x), 0) },
+	"arg":   func(x complex128) complex128 { return complex(cmplx.Phase(x), 0) },
+}
+
+// node is a node of the syntax tree of a formula.
+type node interface {
+	String() string
+}
+
+// num is a constant.
+type num struct {
+	v complex128
+}
+
+// variable is one of the variables z, c and coef.
+type variable string
+
+// neg is the negation of x.
+type neg struct {
+	x node
+}
+
+// binary is the operation x op y.
+type binary struct {
+	op   byte
+	x, y node
+}
+
+// call is the function name applied to x.
+type call struct {
+	name string
+	x    node
+}
+
+func (n num) String() string {
+	if imag(n.v) == 0 {
+		return fmt.Sprint(real(n.v))
+	}
+	return fmt.Sprint(n.v)
+}
+
+func (n variable) String() string { return string(n) }
+func (n neg) String() string      { return "(-" + n.x.String() + ")" }
+func (n call) String() string     { return n.name + "(" + n.x.String() + ")" }
+
+func (n binary) String() string {
+	return "(" + n.x.String() + " " + string(n.op) + " " + n.y.String() + ")"
+}
+
+// constant evaluates the node if it doesn't depend on any variable.
+func constant(n node) (complex128, bool) {
+	switch n := n.(type) {
+	case num:
+		return n.v, true
+	case neg:
+		x, ok := constant(n.x)
+		return -x, ok
+	case call:
+		x, ok := constant(n.x)
+		if !ok {
+			return 0, false
+		}
+		return funcs[n.name](x), true
+	case binary:
+		x, ok := constant(n.x)
+		if !ok {
+			return 0, false
+		}
+		y, ok := constant(n.y)
+		if !ok {
+			return 0, false
+		}
+		return apply(n.op, x, y), true
+	}
+	return 0, false
+}
+
+// apply returns x op y.
+func apply(op byte, x, y complex128) complex128 {
+	switch op {
+	case '+':
+		return x + y
+	case '-':
+		return x - y
+	case '*':
+		return x * y
+	case '/':
+		return x / y
+	}
+	return cmplx.Pow(x, y)
+}
+
+// compile returns a closure which evaluates the node. Constant subexpressions
+// are evaluated once.
+func compile(n node) Func {
+	if v, ok := constant(n); ok {
+		return func(_, _, _ complex128) complex128 { return v }
+	}
+	switch n := n.(type) {
+	case variable:
+		switch n {
+		case "z":
+			return func(z, _, _ complex128) complex128 { return z }
+		case "c":
+			return func(_, c, _ complex128) complex128 { return c }
+		}
+		return func(_, _, coef complex128) complex128 { return coef }
+	case neg:
+		f := compile(n.x)
+		return func(z, c, coef complex128) complex128 { return -f(z, c, coef) }
+	case call:
+		fn, f := funcs[n.name], compile(n.x)
+		return func(z, c, coef complex128) complex128 { return fn(f(z, c, coef)) }
+	case binary:
+		return compileBinary(n)
+	}
+	panic(fmt.Sprintf("formula: unknown node %T", n))
+}
+
+// compileBinary returns a closure which evaluates the binary operation.
+func compileBinary(n binary) Func {
+	f := compile(n.x)
+	if n.op == '^' {
+		if y, ok := constant(n.y); ok {
+			if k := int(real(y)); imag(y) == 0 && float64(k) == real(y) && k >= -mandel.MaxFastPower && k <= mandel.MaxFastPower {
+				return func(z, c, coef complex128) complex128 { return mandel.Pow(f(z, c, coef), k) }
+			}
+		}
+	}
+	g := compile(n.y)
+	switch n.op {
+	case '+':
+		return func(z, c, coef complex128) complex128 { return f(z, c, coef) + g(z, c, coef) }
+	case '-':
+		return func(z, c, coef complex128) complex128 { return f(z, c, coef) - g(z, c, coef) }
+	case '*':
+		return func(z, c, coef complex128) complex128 { return f(z, c, coef) * g(z, c, coef) }
+	case '/':
+		return func(z, c, coef complex128) complex128 { return f(z, c, coef) / g(z, c, coef) }
+	}
+	return func(z, c, coef complex128) complex128 { return cmplx.Pow(f(z, c, coef), g(z, c, coef)) }
+}
@@ -0,0 +1,240 @@
+package formula
+
+import (
+	"fmt"
+	"math"
+)
+
+// Derivative returns the derivative of the expression with respect to the
+// variable v, which is z, c or coef. Functions without complex derivatives,
+// i.e. abs, conj, real, imag and arg, return an error unless their argument is
+// independent of v.
+func (e *Expr) Derivative(v string) (*Expr, error) {
+	switch v {
+	case "z", "c", "coef":
+	default:
+		return nil, fmt.Errorf("formula %q: unknown variable %s", e.src, v)
+	}
+	d, err := derive(e.root, variable(v))
+	if err != nil {
+		return nil, fmt.Errorf("formula %q: %v", e.src, err)
+	}
+	return &Expr{src: d.String(), root: d}, nil
+}
+
+// derive returns the derivative of the node with respect to v.
+func derive(n node, v variable) (node, error) {
+	if !depends(n, v) {
+		return num{0}, nil
+	}
+	switch n := n.(type) {
+	case variable:
+		return num{1}, nil
+	case neg:
+		dx, err := derive(n.x, v)
+		if err != nil {
+			return nil, err
+		}
+		return negate(dx), nil
+	case call:
+		dx, err := derive(n.x, v)
+		if err != nil {
+			return nil, err
+		}
+		df, err := deriveCall(n.name, n.x)
+		if err != nil {
+			return nil, err
+		}
+		// The chain rule.
+		return times(df, dx), nil
+	case binary:
+		return deriveBinary(n, v)
+	}
+	panic(fmt.Sprintf("formula: unknown node %T", n))
+}
+
+// deriveBinary returns the derivative of the binary operation with respect to
+// v.
+func deriveBinary(n binary, v variable) (node, error) {
+	dx, err := derive(n.x, v)
+	if err != nil {
+		return nil, err
+	}
+	dy, err := derive(n.y, v)
+	if err != nil {
+		return nil, err
+	}
+	x, y := n.x, n.y
+	switch n.op {
+	case '+':
+		return plus(dx, dy), nil
+	case '-':
+		return minus(dx, dy), nil
+	case '*':
+		return plus(times(dx, y), times(x, dy)), nil
+	case '/':
+		return over(minus(times(dx, y), times(x, dy)), power(y, num{2})), nil
+	}
+	// The power rule for constant exponents.
+	if k, ok := constant(y); ok {
+		return times(times(num{k}, power(x, num{k - 1})), dx), nil
+	}
+	// x^y = exp(y*log(x)).
+	if !depends(x, v) {
+		return times(times(n, call{"log", x}), dy), nil
+	}
+	return times(n, plus(times(dy, call{"log", x}), over(times(y, dx), x))), nil
+}
+
+// deriveCall returns the derivative of the function name at x.
+func deriveCall(name string, x node) (node, error) {
+	one := num{1}
+	switch name {
+	case "sin":
+		return call{"cos", x}, nil
+	case "cos":
+		return negate(call{"sin", x}), nil
+	case "tan":
+		return plus(one, power(call{"tan", x}, num{2})), nil
+	case "cot":
+		return negate(plus(one, power(call{"cot", x}, num{2}))), nil
+	case "sinh":
+		return call{"cosh", x}, nil
+	case "cosh":
+		return call{"sinh", x}, nil
+	case "tanh":
+		return minus(one, power(call{"tanh", x}, num{2})), nil
+	case "asin":
+		return over(one, call{"sqrt", minus(one, power(x, num{2}))}), nil
+	case "acos":
+		return negate(over(one, call{"sqrt", minus(one, power(x, num{2}))})), nil
+	case "atan":
+		return over(one, plus(one, power(x, num{2}))), nil
+	case "asinh":
+		return over(one, call{"sqrt", plus(power(x, num{2}), one)}), nil
+	case "acosh":
+		return over(one, times(call{"sqrt", minus(x, one)}, call{"sqrt", plus(x, one)})), nil
+	case "atanh":
+		return over(one, minus(one, power(x, num{2}))), nil
+	case "exp":
+		return call{"exp", x}, nil
+	case "log":
+		return over(one, x), nil
+	case "log10":
+		return over(one, times(x, num{math.Ln10})), nil
+	case "sqrt":
+		return over(one, times(num{2}, call{"sqrt", x})), nil
+	}
+	return nil, fmt.Errorf("%s has no complex derivative", name)
+}
+
+// depends returns true if the node depends on the variable v.
+func depends(n node, v variable) bool {
+	switch n := n.(type) {
+	case variable:
+		return n == v
+	case neg:
+		return depends(n.x, v)
+	case call:
+		return depends(n.x, v)
+	case binary:
+		return depends(n.x, v) || depends(n.y, v)
+	}
+	return false
+}
+
+// The constructors below fold constants and drop the zeros and ones of the
+// derivative rules, to keep the derivatives small.
+
+// isNum returns true if the node is the constant v.
+func isNum(n node, v complex128) bool {
+	x, ok := n.(num)
+	return ok && x.v == v
+}
+
+// fold returns the constant x op y, if both are constants.
+func fold(op byte, x, y node) (node, bool) {
+	a, ok := x.(num)
+	if !ok {
+		return nil, false
+	}
+	b, ok := y.(num)
+	if !ok {
+		return nil, false
+	}
+	return num{apply(op, a.v, b.v)}, true
+}
+
+func negate(x node) node {
+	if a, ok := x.(num); ok {
+		return num{-a.v}
+	}
+	if a, ok := x.(neg); ok {
+		return a.x
+	}
+	return neg{x}
+}
+
+func plus(x, y node) node {
+	if n, ok := fold('+', x, y); ok {
+		return n
+	}
+	if isNum(x, 0) {
+		return y
+	}
+	if isNum(y, 0) {
+		return x
+	}
+	return binary{'+', x, y}
+}
+
+func minus(x, y node) node {
+	if n, ok := fold('-', x, y); ok {
+		return n
+	}
+	if isNum(x, 0) {
+		return negate(y)
+	}
+	if isNum(y, 0) {
+		return x
+	}
+	return binary{'-', x, y}
+}
+
+func times(x, y node) node {
+	if n, ok := fold('*', x, y); ok {
+		return n
+	}
+	switch {
+	case isNum(x, 0), isNum(y, 0):
+		return num{0}
+	case isNum(x, 1):
+		return y
+	case isNum(y, 1):
+		return x
+	}
+	return binary{'*', x, y}
+}
+
+func over(x, y node) node {
+	if n, ok := fold('/', x, y); ok {
+		return n
+	}
+	if isNum(y, 1) {
+		return x
+	}
+	return binary{'/', x, y}
+}
+
+func power(x, y node) node {
+	if n, ok := fold('^', x, y); ok {
+		return n
+	}
+	switch {
+	case isNum(y, 0):
+		return num{1}
+	case isNum(y, 1):
+		return x
+	}
+	return binary{'^', x, y}
+}
@@ -0,0 +1,332 @@
+// Package formula parses complex functions such as "z^3 + c*sin(z) - coef"
+// and compiles them into closures which can be explored like the functions of
+// package mandel.
+//
+// The variables are z, c and coef, and the constants are i, pi and e. Numbers
+// may have an imaginary suffix, e.g. 0.5i. The operators are +, -, *, / and ^,
+// with the usual precedence, where ^ is right associative and binds tighter
+// than unary minus. |x| is the absolute value of x. The functions are the
+// functions of math/cmplx together with abs, conj, real and imag, which return
+// complex numbers with zero imaginary parts where necessary:
+//
+//	sin cos tan cot sinh cosh tanh asin acos atan asinh acosh atanh
+//	exp log log10 sqrt abs conj real imag arg
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Func is a compiled complex function.
+type Func func(z, c, coef complex128) complex128
+
+// Expr is a parsed complex function.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Error is an error in a formula, at the byte offset Pos. The error message
+// tells the column of the character, which is counted in runes.
+type Error struct {
+	Formula string
+	Pos     int
+	Msg     string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("formula %q: column %d: %s", e.Formula, utf8.RuneCountInString(e.Formula[:e.Pos])+1, e.Msg)
+}
+
+// Parse parses the formula src.
+func Parse(src string) (expr *Expr, err error) {
+	p := &parser{src: src}
+	// The parser panics with *Error to unwind the recursion.
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	p.next()
+	root := p.expr()
+	if p.tok.kind != eof {
+		p.errorf("unexpected %s", p.tok)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Compile parses the formula src and compiles it.
+func Compile(src string) (Func, error) {
+	expr, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Func(), nil
+}
+
+// Func compiles the expression into a closure.
+func (e *Expr) Func() Func {
+	return compile(e.root)
+}
+
+// String returns the fully parenthesized expression.
+func (e *Expr) String() string {
+	return e.root.String()
+}
+
+// kind is the kind of a token.
+type kind int
+
+const (
+	eof kind = iota
+	number
+	ident
+	op
+)
+
+// token is a lexical token of a formula.
+type token struct {
+	kind kind
+	text string
+	pos  int
+	val  complex128 // Value of numbers.
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case eof:
+		return "end of formula"
+	case number:
+		return "number " + t.text
+	case ident:
+		return "identifier " + t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// parser is a recursive descent parser of formulas.
+type parser struct {
+	src string
+	pos int   // Offset of the next token.
+	tok token // Current token.
+}
+
+// errorf aborts the parse with an error at the current token.
+func (p *parser) errorf(format string, a ...interface{}) {
+	panic(&Error{Formula: p.src, Pos: p.tok.pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+// peek returns the rune at the byte offset pos and its width in bytes, or
+// utf8.RuneError and zero at the end of the formula.
+func (p *parser) peek(pos int) (rune, int) {
+	return utf8.DecodeRuneInString(p.src[pos:])
+}
+
+// skip advances past the runes which satisfy f.
+func (p *parser) skip(f func(rune) bool) {
+	for p.pos < len(p.src) {
+		ch, w := p.peek(p.pos)
+		if !f(ch) {
+			return
+		}
+		p.pos += w
+	}
+}
+
+// next scans the next token.
+func (p *parser) next() {
+	p.skip(unicode.IsSpace)
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: eof, pos: start}
+		return
+	}
+	ch, w := p.peek(p.pos)
+	switch {
+	case unicode.IsDigit(ch) || ch == '.':
+		p.scanNumber()
+	case unicode.IsLetter(ch) || ch == '_':
+		p.skip(isIdent)
+		p.tok = token{kind: ident, text: p.src[start:p.pos], pos: start}
+	case strings.ContainsRune("+-*/^()|", ch):
+		p.pos += w
+		p.tok = token{kind: op, text: string(ch), pos: start}
+	default:
+		p.tok = token{pos: start}
+		p.errorf("unexpected character %q", ch)
+	}
+}
+
+// scanNumber scans a real or imaginary number.
+func (p *parser) scanNumber() {
+	start := p.pos
+	p.skip(unicode.IsDigit)
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		p.skip(unicode.IsDigit)
+	}
+	// The exponent must have digits, to tell it from the constant e.
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		k := p.pos + 1
+		if k < len(p.src) && (p.src[k] == '+' || p.src[k] == '-') {
+			k++
+		}
+		if ch, _ := p.peek(k); unicode.IsDigit(ch) {
+			p.pos = k
+			p.skip(unicode.IsDigit)
+		}
+	}
+	text := p.src[start:p.pos]
+	p.tok = token{kind: number, text: text, pos: start}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		p.errorf("invalid number %q", text)
+	}
+	p.tok.val = complex(v, 0)
+	// Imaginary suffix.
+	if p.pos < len(p.src) && p.src[p.pos] == 'i' && !p.ident(p.pos+1) {
+		p.pos++
+		p.tok.text = p.src[start:p.pos]
+		p.tok.val = complex(0, v)
+	}
+	if p.ident(p.pos) {
+		p.tok.pos = p.pos
+		p.errorf("missing operator before %q", p.src[p.pos:])
+	}
+}
+
+// ident returns true if the rune at the byte offset pos may be part of an
+// identifier.
+func (p *parser) ident(pos int) bool {
+	ch, _ := p.peek(pos)
+	return isIdent(ch)
+}
+
+// isIdent returns true if ch may be part of an identifier.
+func isIdent(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_'
+}
+
+// is returns true if the current token is the operator s.
+func (p *parser) is(s string) bool {
+	return p.tok.kind == op && p.tok.text == s
+}
+
+// expect consumes the operator s.
+func (p *parser) expect(s string) {
+	if !p.is(s) {
+		p.errorf("expected %q, found %s", s, p.tok)
+	}
+	p.next()
+}
+
+// expr parses a sum of terms.
+//
+//	expr = term { ("+" | "-") term }
+func (p *parser) expr() node {
+	x := p.term()
+	for p.is("+") || p.is("-") {
+		o := p.tok.text[0]
+		p.next()
+		x = binary{op: o, x: x, y: p.term()}
+	}
+	return x
+}
+
+// term parses a product of factors.
+//
+//	term = unary { ("*" | "/") unary }
+func (p *parser) term() node {
+	x := p.unary()
+	for p.is("*") || p.is("/") {
+		o := p.tok.text[0]
+		p.next()
+		x = binary{op: o, x: x, y: p.unary()}
+	}
+	return x
+}
+
+// unary parses a signed power.
+//
+//	unary = ("-" | "+") unary | power
+func (p *parser) unary() node {
+	switch {
+	case p.is("-"):
+		p.next()
+		return neg{x: p.unary()}
+	case p.is("+"):
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+// power parses a right associative power.
+//
+//	power = primary [ "^" unary ]
+func (p *parser) power() node {
+	x := p.primary()
+	if p.is("^") {
+		p.next()
+		return binary{op: '^', x: x, y: p.unary()}
+	}
+	return x
+}
+
+// primary parses numbers, variables, constants, function calls, parenthesized
+// expressions and absolute values.
+//
+//	primary = number | name | name "(" expr ")" | "(" expr ")" | "|" expr "|"
+func (p *parser) primary() node {
+	tok := p.tok
+	switch {
+	case tok.kind == number:
+		p.next()
+		return num{tok.val}
+	case tok.kind == ident:
+		p.next()
+		if p.is("(") {
+			if _, ok := funcs[tok.text]; !ok {
+				p.tok = tok
+				p.errorf("unknown function %s", tok.text)
+			}
+			p.next()
+			x := p.expr()
+			p.expect(")")
+			return call{name: tok.text, x: x}
+		}
+		if v, ok := constants[tok.text]; ok {
+			return num{v}
+		}
+		if _, ok := funcs[tok.text]; ok {
+			p.tok = tok
+			p.errorf("missing argument of function %s", tok.text)
+		}
+		switch tok.text {
+		case "z", "c", "coef":
+			return variable(tok.text)
+		}
+		p.tok = tok
+		p.errorf("unknown variable %s", tok.text)
+	case p.is("("):
+		p.next()
+		x := p.expr()
+		p.expect(")")
+		return x
+	case p.is("|"):
+		p.next()
+		x := p.expr()
+		p.expect("|")
+		return call{name: "abs", x: x}
+	}
+	p.errorf("unexpected %s", tok)
+	return nil
+}
@@ -0,0 +1,129 @@
+package formula
+
+import (
+	"math"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	golden := []struct {
+		src  string
+		want func(z, c, coef complex128) complex128
+	}{
+		{"z^2 + c", func(z, c, _ complex128) complex128 { return z*z + c }},
+		{"z^3 + c*sin(z) - coef", func(z, c, coef complex128) complex128 { return z*z*z + c*cmplx.Sin(z) - coef }},
+		{"coef*z*z + coef*c", func(z, c, coef complex128) complex128 { return coef*z*z + coef*c }},
+		{"-z^2", func(z, _, _ complex128) complex128 { return -z * z }},
+		{"2^3^2", func(_, _, _ complex128) complex128 { return 512 }},
+		{"z^-2 + 1.5e-1i", func(z, _, _ complex128) complex128 { return 1/(z*z) + 0.15i }},
+		{"z^2.5 + c", func(z, c, _ complex128) complex128 { return cmplx.Pow(z, 2.5) + c }},
+		{"z^c", func(z, c, _ complex128) complex128 { return cmplx.Pow(z, c) }},
+		{"(abs(real(z)) + i*abs(imag(z)))^2 + c", func(z, c, _ complex128) complex128 {
+			r, i := math.Abs(real(z)), math.Abs(imag(z))
+			return complex(r, i)*complex(r, i) + c
+		}},
+		{"|z| + conj(c) / 2", func(z, c, _ complex128) complex128 { return complex(cmplx.Abs(z), 0) + cmplx.Conj(c)/2 }},
+		{"exp(pi*i) + e", func(_, _, _ complex128) complex128 { return cmplx.Exp(math.Pi*1i) + math.E }},
+		{"cot(c)*atanh(z) + c", func(z, c, _ complex128) complex128 { return cmplx.Cot(c)*cmplx.Atanh(z) + c }},
+	}
+	z, c, coef := complex(0.3, -0.7), complex(-0.2, 0.5), complex(1.1, 0.2)
+	for _, g := range golden {
+		f, err := Compile(g.src)
+		if err != nil {
+			t.Errorf("%q: %v", g.src, err)
+			continue
+		}
+		got, want := f(z, c, coef), g.want(z, c, coef)
+		if cmplx.Abs(got-want) > 1e-12*math.Max(1, cmplx.Abs(want)) {
+			t.Errorf("%q: got %v, want %v", g.src, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	golden := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{"z^2 +", 5, "unexpected end of formula"},
+		{"z^2 + x", 6, "unknown variable x"},
+		{"foo(z) + c", 0, "unknown function foo"},
+		{"sin + c", 0, "missing argument of function sin"},
+		{"(z + c", 6, `expected ")"`},
+		{"2z + c", 1, "missing operator"},
+		{"z # c", 2, "unexpected character"},
+		{"z c", 2, "unexpected identifier c"},
+		{"z · c", 2, "unexpected character '·'"},
+		{"π + z", 0, "unknown variable π"},
+		{"z² + c", 1, "unexpected character '²'"},
+		{"2π", 1, "missing operator before \"π\""},
+	}
+	for _, g := range golden {
+		_, err := Parse(g.src)
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%q: expected *Error, got %v", g.src, err)
+			continue
+		}
+		if e.Pos != g.pos || !strings.Contains(e.Msg, g.msg) {
+			t.Errorf("%q: got error %q at %d, want %q at %d", g.src, e.Msg, e.Pos, g.msg, g.pos)
+		}
+	}
+	// The columns are counted in runes, not bytes.
+	if _, err := Parse("π·z"); err == nil || !strings.Contains(err.Error(), "column 2") {
+		t.Errorf("got error %v, want it at column 2", err)
+	}
+}
+
+func TestDerivative(t *testing.T) {
+	srcs := []string{
+		"z^3 - 1",
+		"z^2 + c",
+		"-z^-2 + 3*z",
+		"(z^2 + 1) / (z - c)",
+		"z^2.5 + c*z",
+		"2^z + z^z",
+		"sin(z)*cos(z) + tan(z) - cot(z)",
+		"sinh(z) + cosh(z)*tanh(z)",
+		"asin(z) + acos(z) + atan(z)",
+		"asinh(z) + acosh(z) + atanh(z)",
+		"exp(z^2) + log(z) + log10(z) + sqrt(z)",
+		"|c| * z + real(c)",
+	}
+	z, c, coef := complex(0.3, -0.7), complex(-0.2, 0.5), complex(1.1, 0.2)
+	const h = 1e-6
+	for _, src := range srcs {
+		expr, err := Parse(src)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		d, err := expr.Derivative("z")
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		// The derivative survives a round trip through its source.
+		d, err = Parse(d.String())
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		f := expr.Func()
+		want := (f(z+h, c, coef) - f(z-h, c, coef)) / (2 * h)
+		if got := d.Func()(z, c, coef); cmplx.Abs(got-want) > 1e-6*math.Max(1, cmplx.Abs(want)) {
+			t.Errorf("%q: derivative %s is %v, want %v", src, d, got, want)
+		}
+	}
+	for _, src := range []string{"|z|", "conj(z) + z", "real(z^2)"} {
+		expr, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := expr.Derivative("z"); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package fractal
+
+// Accumulation determines how the workers add their orbits to the histograms.
+type Accumulation int
+
+const (
+	// Buffered lets each chunk of orbit attempts add its orbits to its own
+	// histograms, which are added together in the order of the chunks. The
+	// renders are reproducible, but each lane needs memory for a full set of
+	// histograms.
+	Buffered Accumulation = iota
+	// Atomic adds the orbits directly to the histograms with atomic
+	// operations.
+	Atomic
+	// Locked adds the orbits directly to the histograms while holding one of
+	// a fixed number of locks.
+	Locked
+)
+
+func (a Accumulation) String() string {
+	switch a {
+	case Buffered:
+		return "Buffered"
+	case Atomic:
+		return "Atomic"
+	case Locked:
+		return "Locked"
+	default:
+		return "fail"
+	}
+}
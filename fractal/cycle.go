@@ -0,0 +1,29 @@
+package fractal
+
+// CycleDetection determines the strategy used to detect periodic orbits.
+type CycleDetection int
+
+const (
+	// Backoff compares each point against a saved point, which is replaced at
+	// every iteration which is a power of two.
+	Backoff CycleDetection = iota
+	// Brent uses Brent's algorithm, which replaces the saved point when the
+	// distance to it reaches the next power of two.
+	Brent
+	// NoDetection iterates all orbits until they escape or reach the
+	// iteration limit.
+	NoDetection
+)
+
+func (d CycleDetection) String() string {
+	switch d {
+	case Backoff:
+		return "Backoff"
+	case Brent:
+		return "Brent"
+	case NoDetection:
+		return "NoDetection"
+	default:
+		return "fail"
+	}
+}
@@ -9,6 +9,7 @@ import (
 	rand7i "github.com/7i/rand"
 
 	"github.com/karlek/wasabi/coloring"
+	"github.com/karlek/wasabi/dd"
 	"github.com/karlek/wasabi/histo"
 	"github.com/karlek/wasabi/util"
 )
@@ -23,28 +24,56 @@ type Fractal struct {
 	PlotImportance bool        // Create an image of the sampling points color graded by their importance.
 
 	// Function specific options.
-	Iterations int64                                                // Number of iterations before assuming convergence.
-	Bailout    float64                                              // (Squared) bailout radius.
-	Plane      func(complex128, complex128) complex128              // Function to chose the capital plane.
-	Func       func(complex128, complex128, complex128) complex128  // The complex function to explore!
-	Register   func(complex128, complex128, *Orbit, *Fractal) int64 // Registering function for the orbits.
-	Coef       complex128                                           // Complex coefficient used in the complex function.
+	Iterations  int64                                                // Number of iterations before assuming convergence.
+	Bailout     float64                                              // (Squared) bailout radius.
+	Plane       func(complex128, complex128) complex128              // Function to chose the capital plane.
+	Func        func(complex128, complex128, complex128) complex128  // The complex function to explore!
+	NewIterator func() Iterator                                      // Creates the iterators of the orbits of complex functions which depend on earlier points of the orbits, and replaces Func. Nil for stateless complex functions.
+	Reject      func(complex128, *Fractal) bool                      // Fast test of points c whose orbits are known to stay bounded. Nil if the complex function has none.
+	FuncDC      func(z, dz, c, coef complex128) complex128           // Derivative with respect to c of the point after z in an orbit, where dz is the derivative of z. Nil if the complex function has none.
+	Register    func(complex128, complex128, *Orbit, *Fractal) int64 // Registering function for the orbits.
+	Roots       []complex128                                         // Roots of Newton's method, whose indices number the basins of the orbits. Nil unless the orbits are registered by Newton's method.
+	Coef        complex128                                           // Complex coefficient used in the complex function.
+
+	// Precision specific options.
+	Precision Precision                              // Arithmetic used to iterate the orbits.
+	FuncDD    func(z, c, coef dd.Complex) dd.Complex // The complex function in double-double precision. Nil if the complex function has none.
+	OffsetLo  complex128                             // Low parts of the offset, for double-double precision.
+
+	// Cycle detection specific options.
+	CycleDetection CycleDetection // Strategy used to detect periodic orbits.
+	Epsilon        float64        // Largest distance between two points of an orbit which are considered equal.
 
 	// Rendering specific options.
 	Zoom   float64    // Zoom level of our render.
 	Offset complex128 // Offset the camera center for the render.
 
 	// Sampling specific options.
+	Sampler   Sampler // Strategy used to sample the points of the orbits.
 	Tries     float64 // Number of orbit attempts we will sample.
 	Seed      int64   // The random seed we sample random points from.
+	Streams   int64   // Number of random streams split from the seed which have been sampled.
 	Threshold int64   // Threshold length of orbits.
 
+	Accumulation Accumulation // How the workers add their orbits to the histograms.
+	Lanes        int          // Number of chunks of orbit attempts rendered ahead of the oldest unfinished chunk, or zero for twice the number of workers.
+
+	// Importance sampling specific options.
+	ImportanceTries      float64 // Share of the tries used by the first pass.
+	ImportanceResolution int     // Width and height of the sampling distribution.
+
 	// Coloring method specific options.
 	PathPoints  int64 // Number of intermediate points used for path interpolation.
 	BezierLevel int   // Bezier interpolation level: 1 is linear, 2 is quadratic etc.
 
+	// Updates of the starting point z from the sampled point, and of the
+	// sampled point from the previous one.
 	Z, C func(complex128, *rand7i.ComplexRNG) complex128
 
+	// Julia set specific options.
+	Julia  bool       // Fix c at JuliaC and sample the starting points z instead.
+	JuliaC complex128 // The parameter c of the julia set.
+
 	// Calculation specific.
 	ratio float64
 	xZoom float64
@@ -127,10 +156,18 @@ func (frac *Fractal) String() string {
 	fmt.Fprintf(w, "Iterations:\t%d\n", frac.Iterations)
 	fmt.Fprintf(w, "Plane:\t%v\n", util.FunctionName(frac.Plane))
 	fmt.Fprintf(w, "Coef:\t%v\n", frac.Coef)
+	if frac.Julia {
+		fmt.Fprintf(w, "Julia:\t%v\n", frac.JuliaC)
+	}
 	fmt.Fprintf(w, "Bail:\t%f\n", frac.Bailout)
+	fmt.Fprintf(w, "Cycles:\t%v (%g)\n", frac.CycleDetection, frac.Epsilon)
+	fmt.Fprintf(w, "Precision:\t%v\n", frac.Precision)
 	fmt.Fprintf(w, "Zoom:\t%f\n", frac.Zoom)
 	fmt.Fprintf(w, "Offset:\t%v\n", frac.Offset)
 	fmt.Fprintf(w, "Seed:\t%d\n", frac.Seed)
+	fmt.Fprintf(w, "Sampler:\t%v\n", frac.Sampler)
+	fmt.Fprintf(w, "Accumulation:\t%v\n", frac.Accumulation)
+	fmt.Fprintf(w, "Lanes:\t%d\n", frac.Lanes)
 	fmt.Fprintf(w, "Points:\t%d\n", frac.PathPoints)
 	fmt.Fprintf(w, "Tries:\t%.f\n", frac.Tries)
 	w.Flush()
@@ -156,11 +193,26 @@ func (frac Fractal) Y(i float64) int {
 	return int(frac.yZoom*(i+imag(frac.Offset)) + float64(frac.Height)/2.0)
 }
 
+// Start returns the starting points (z, c) of an orbit from the sampled point
+// p, where z is given by the Z update of p. The sampled point is c, unless the
+// fractal is a julia set where c is fixed.
+func (frac *Fractal) Start(p complex128, rng *rand7i.ComplexRNG) (z, c complex128) {
+	if frac.Julia {
+		return frac.Z(p, rng), frac.JuliaC
+	}
+	return frac.Z(p, rng), p
+}
+
 // RandomPoint initializes each iteration with a random point.
 func RandomPoint(_ complex128, rng *rand7i.ComplexRNG) complex128 {
 	return rng.Complex128Go()
 }
 
+// SampledPoint initializes each iteration with the sampled point itself.
+func SampledPoint(p complex128, _ *rand7i.ComplexRNG) complex128 {
+	return p
+}
+
 func Importance(frac *Fractal) *Fractal {
 	f := Fractal{
 		Width:  frac.Width,
@@ -175,8 +227,13 @@ func Importance(frac *Fractal) *Fractal {
 }
 
 func (frac *Fractal) Point(z, c complex128) (image.Point, bool) {
+	return frac.PointLo(z, 0, c)
+}
+
+// PointLo is Point for the double-double point z + lo.
+func (frac *Fractal) PointLo(z, lo, c complex128) (image.Point, bool) {
 	// Convert the 4-d point to a pixel coordinate.
-	p := frac.ComplexToImage(z, c)
+	p := frac.ComplexToImageLo(z, lo, c)
 
 	// Ignore points outside image.
 	if p.X >= frac.Width || p.Y >= frac.Height || p.X < 0 || p.Y < 0 {
@@ -225,10 +282,20 @@ func (frac *Fractal) ComplexToImage(z, c complex128) (p image.Point) {
 
 	// tmp := frac.Plane(complex(rotVec.At(0, 0), rotVec.At(1, 0)),
 	// complex(rotVec.At(2, 0), rotVec.At(3, 0)))
+	return frac.ComplexToImageLo(z, 0, c)
+}
+
+// ComplexToImageLo is ComplexToImage for the double-double point z + lo. The
+// planes only select parts of the points, so the low parts of the plane are
+// selected from the low part of z, since c is never double-double.
+func (frac *Fractal) ComplexToImageLo(z, lo, c complex128) (p image.Point) {
 	tmp := frac.Plane(z, c)
+	tmpLo := frac.Plane(lo, 0)
 
-	p.X = frac.X(real(tmp))
-	p.Y = frac.Y(imag(tmp))
+	// The high parts of the point and the offset are close at deep zooms, so
+	// their sum is exact before the low parts are added.
+	p.X = int(frac.xZoom*((real(tmp)+real(frac.Offset))+(real(tmpLo)+real(frac.OffsetLo))) + float64(frac.Width)/2.0)
+	p.Y = int(frac.yZoom*((imag(tmp)+imag(frac.Offset))+(imag(tmpLo)+imag(frac.OffsetLo))) + float64(frac.Height)/2.0)
 
 	return p
 }
@@ -0,0 +1,19 @@
+package fractal
+
+// Iterator iterates an orbit with a complex function whose next point may
+// depend on earlier points of the orbit, e.g. the phoenix. An iterator keeps
+// the state of a single orbit, and a new iterator is created for each orbit.
+type Iterator interface {
+	// Next returns the point after z in the orbit of c.
+	Next(z, c, coef complex128) complex128
+}
+
+// Step returns the function which iterates a new orbit of the fractal. It's
+// the complex function of the fractal itself if it's stateless, which spares
+// the registering functions an indirection in their inner loops.
+func (frac *Fractal) Step() func(z, c, coef complex128) complex128 {
+	if frac.NewIterator != nil {
+		return frac.NewIterator().Next
+	}
+	return frac.Func
+}
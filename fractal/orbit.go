@@ -3,5 +3,72 @@ package fractal
 // Orbit represents the orbit of points visited from iterating the complex function.
 type Orbit struct {
 	Points []complex128
+	Lows   []complex128 // Low parts of the points of double-double orbits, or nil.
 	C      complex128
+	Weight float64 // Multiplier of the orbit's contribution to the histograms.
+
+	Intensity float64 // Multiplier of the orbit's contribution set by the registering function, reset to one before each orbit.
+
+	Outcome Outcome // How the last iteration of the orbit ended, as told by the registering function.
+	Length  int64   // Number of iterations of the last orbit, or -1 if it was rejected.
+	Period  int64   // Period of the last orbit, if its outcome was a cycle.
+	Root    int     // Index of the root of the fractal which the last orbit converged to, if its outcome was a root, or -1 if it's none of them.
+	Outside int64   // Points of the last registered orbit outside the image, unless it was registered as paths.
+}
+
+// NewOrbit returns an orbit with room for the points of the given number of
+// iterations.
+func NewOrbit(iterations int64) *Orbit {
+	return &Orbit{Points: make([]complex128, iterations), Weight: 1, Intensity: 1}
+}
+
+// Low returns the low part of the i-th point of a double-double orbit, or
+// zero if the orbit has none.
+func (o *Orbit) Low(i int64) complex128 {
+	if o.Lows == nil {
+		return 0
+	}
+	return o.Lows[i]
+}
+
+// Outcome is how the iteration of an orbit ended.
+type Outcome int
+
+const (
+	// Unknown is the outcome of registering functions which doesn't tell.
+	Unknown Outcome = iota
+	// Escaped orbits left the bailout radius.
+	Escaped
+	// Converged orbits stayed inside the bailout radius for all iterations.
+	Converged
+	// Cycle orbits were found to be periodic by the cycle detection.
+	Cycle
+	// InBulb orbits were never iterated, since their point c is known to be
+	// inside one of the larger bulbs of the mandelbrot.
+	InBulb
+	// Untrapped orbits were iterated, but never came near the orbit trap.
+	Untrapped
+	// Root orbits converged to a root by Newton's method.
+	Root
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Unknown:
+		return "Unknown"
+	case Escaped:
+		return "Escaped"
+	case Converged:
+		return "Converged"
+	case Cycle:
+		return "Cycle"
+	case InBulb:
+		return "InBulb"
+	case Untrapped:
+		return "Untrapped"
+	case Root:
+		return "Root"
+	default:
+		return "fail"
+	}
 }
@@ -0,0 +1,24 @@
+package fractal
+
+// Precision determines the arithmetic used to iterate the orbits.
+type Precision int
+
+const (
+	// Double iterates the orbits with complex128.
+	Double Precision = iota
+	// DoubleDouble iterates the orbits with double-double arithmetic, which
+	// keeps about 32 significant digits. The high parts of the points are
+	// stored in the orbits as usual, and the low parts separately.
+	DoubleDouble
+)
+
+func (p Precision) String() string {
+	switch p {
+	case Double:
+		return "Double"
+	case DoubleDouble:
+		return "DoubleDouble"
+	default:
+		return "fail"
+	}
+}
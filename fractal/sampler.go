@@ -0,0 +1,29 @@
+package fractal
+
+// Sampler determines the strategy used to chose the points which are iterated.
+type Sampler int
+
+const (
+	// Uniform samples points uniformly from the domain and searches nearby
+	// points of long orbits.
+	Uniform Sampler = iota
+	// Metropolis samples points with the Metropolis-Hastings algorithm by
+	// mutating previously accepted points.
+	Metropolis
+	// ImportanceSampling samples points from a distribution built by a low
+	// resolution first pass.
+	ImportanceSampling
+)
+
+func (s Sampler) String() string {
+	switch s {
+	case Uniform:
+		return "Uniform"
+	case Metropolis:
+		return "Metropolis"
+	case ImportanceSampling:
+		return "ImportanceSampling"
+	default:
+		return "fail"
+	}
+}
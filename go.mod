@@ -10,11 +10,9 @@ require (
 	github.com/go-gl/gl v0.0.0-20190320180904-bf2b1f2f34d7 // indirect
 	github.com/go-gl/glfw v0.0.0-20190409004039-e6da0acd62b1 // indirect
 	github.com/go-gl/mathgl v0.0.0-20190713194549-592312d8590a // indirect
-	github.com/karlek/progress v0.0.0-20180531012107-218076c9140c
 	github.com/klauspost/cpuid v1.2.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.0.2
 	github.com/pkg/errors v0.8.1 // indirect
 	github.com/pkg/profile v1.3.0
 	github.com/sirupsen/logrus v1.4.2
-	github.com/wayneashleyberry/terminal-dimensions v1.0.0 // indirect
 )
@@ -0,0 +1,57 @@
+package histo
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// stripes is the number of locks shared by the bins of all histograms.
+const stripes = 1024
+
+// locks guards the bins of histograms which are added to with AddLocked.
+var locks [stripes]sync.Mutex
+
+// AddAtomic adds v to the bin (x, y) of the histogram atomically. Additions
+// made concurrently are all kept, but their order is undefined.
+func AddAtomic(h Histo, x, y int, v float64) {
+	addr := (*uint64)(unsafe.Pointer(&h[x][y]))
+	for {
+		old := atomic.LoadUint64(addr)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(addr, old, sum) {
+			return
+		}
+	}
+}
+
+// AddLocked adds v to the bin (x, y) of the histogram while holding the lock
+// of its stripe.
+func AddLocked(h Histo, x, y int, v float64) {
+	mu := &locks[(x*len(h[x])+y)%stripes]
+	mu.Lock()
+	h[x][y] += v
+	mu.Unlock()
+}
+
+// Reduce adds the histograms srcs to dst in the order they are given, and
+// clears them. The columns are reduced in parallel, but the result is the same
+// as a sequential reduction.
+func Reduce(dst Histo, srcs ...Histo) {
+	wg := new(sync.WaitGroup)
+	wg.Add(len(dst))
+	for x := range dst {
+		go func(x int) {
+			defer wg.Done()
+			col := dst[x]
+			for _, src := range srcs {
+				for y, v := range src[x] {
+					col[y] += v
+					src[x][y] = 0
+				}
+			}
+		}(x)
+	}
+	wg.Wait()
+}
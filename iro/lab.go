@@ -0,0 +1,85 @@
+package iro
+
+import "math"
+
+// lab is a color in the perceptual CIELAB color space with the D65 white
+// point, where euclidean distances approximate perceived differences.
+type lab struct {
+	L, A, B float64
+}
+
+// D65 reference white.
+const (
+	whiteX = 0.95047
+	whiteY = 1.00000
+	whiteZ = 1.08883
+)
+
+// toLab converts the sRGB color to CIELAB.
+func toLab(c RGBA) lab {
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / whiteX
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) / whiteY
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / whiteZ
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// rgba converts the CIELAB color to an opaque sRGB color, clamped to the sRGB
+// gamut.
+func (c lab) rgba() RGBA {
+	fy := (c.L + 16) / 116
+	fx, fz := fy+c.A/500, fy-c.B/200
+	x, y, z := labFInv(fx)*whiteX, labFInv(fy)*whiteY, labFInv(fz)*whiteZ
+	r := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b := 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return RGBA{R: gamma(r), G: gamma(g), B: gamma(b), A: 1}
+}
+
+// hue returns the hue angle of the color in radians.
+func (c lab) hue() float64 {
+	return math.Atan2(c.B, c.A)
+}
+
+// dist returns the squared distance between the colors.
+func (c lab) dist(o lab) float64 {
+	l, a, b := c.L-o.L, c.A-o.A, c.B-o.B
+	return l*l + a*a + b*b
+}
+
+// linear converts an sRGB channel to linear light.
+func linear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// gamma converts a linear light channel to sRGB, clamped to [0, 1].
+func gamma(v float64) float64 {
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return math.Max(0, math.Min(1, v))
+}
+
+// labF is the non-linear compression of CIELAB.
+func labF(t float64) float64 {
+	const delta = 6.0 / 29.0
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29.0
+}
+
+// labFInv is the inverse of labF.
+func labFInv(t float64) float64 {
+	const delta = 6.0 / 29.0
+	if t > delta {
+		return t * t * t
+	}
+	return 3 * delta * delta * (t - 4.0/29.0)
+}
@@ -0,0 +1,178 @@
+package iro
+
+import (
+	"errors"
+	"image"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Order is the order of the colors of a palette.
+type Order int
+
+const (
+	// ByLuminance orders the colors from dark to light.
+	ByLuminance Order = iota
+	// ByHue orders the colors by their CIELAB hue angle, from teal through
+	// blue, purple, red, yellow and green.
+	ByHue
+)
+
+func (o Order) String() string {
+	switch o {
+	case ByLuminance:
+		return "ByLuminance"
+	case ByHue:
+		return "ByHue"
+	default:
+		return "fail"
+	}
+}
+
+// maxSamples is the largest number of pixels clustered by Palette; larger
+// images are sampled evenly.
+const maxSamples = 1 << 14
+
+// Palette returns n representative colors of the image in the order. The
+// colors are the centers of a k-means clustering of the opaque pixels of the
+// image in the CIELAB color space, where distances follow how different the
+// colors look. It returns nil if the image has no opaque pixels.
+func Palette(img image.Image, n int, order Order) []RGBA {
+	pixels := samples(img)
+	if len(pixels) == 0 || n <= 0 {
+		return nil
+	}
+	centers := kmeans(pixels, n)
+	switch order {
+	case ByHue:
+		sort.SliceStable(centers, func(i, j int) bool { return centers[i].hue() < centers[j].hue() })
+	default:
+		sort.SliceStable(centers, func(i, j int) bool { return centers[i].L < centers[j].L })
+	}
+	palette := make([]RGBA, len(centers))
+	for i, c := range centers {
+		palette[i] = c.rgba()
+	}
+	return palette
+}
+
+// PaletteStops returns the n colors of the palette of the image with evenly
+// spaced stops, the colors and stops of a gradient. It returns an error if the
+// image has no opaque pixels.
+func PaletteStops(img image.Image, n int, order Order) ([]Color, []float64, error) {
+	palette := Palette(img, n, order)
+	if len(palette) == 0 {
+		return nil, nil, errors.New("the image has no opaque pixels")
+	}
+	// A gradient needs two stops.
+	if len(palette) == 1 {
+		palette = append(palette, palette[0])
+	}
+	stops := make([]float64, len(palette))
+	for i := range stops {
+		stops[i] = float64(i) / float64(len(stops)-1)
+	}
+	return ToColors(palette), stops, nil
+}
+
+// samples returns the opaque pixels of an even grid over the image, with at
+// most about maxSamples pixels.
+func samples(img image.Image) []lab {
+	bounds := img.Bounds()
+	step := int(math.Ceil(math.Sqrt(float64(bounds.Dx()*bounds.Dy()) / maxSamples)))
+	if step < 1 {
+		step = 1
+	}
+	var pixels []lab
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a == 0 {
+				continue
+			}
+			// Undo the premultiplied alpha.
+			pixels = append(pixels, toLab(RGBA{
+				R: float64(r) / float64(a),
+				G: float64(g) / float64(a),
+				B: float64(b) / float64(a),
+				A: 1,
+			}))
+		}
+	}
+	return pixels
+}
+
+// kmeans clusters the pixels into k clusters and returns their centers. The
+// centers are seeded by k-means++ from a fixed seed, so the palette of an
+// image is always the same.
+func kmeans(pixels []lab, k int) []lab {
+	rng := rand.New(rand.NewSource(1))
+
+	// k-means++ picks each seed with a probability proportional to its
+	// squared distance to the closest seed so far.
+	centers := []lab{pixels[rng.Intn(len(pixels))]}
+	dists := make([]float64, len(pixels))
+	for len(centers) < k {
+		var sum float64
+		for i, p := range pixels {
+			dists[i] = p.dist(centers[nearest(p, centers)])
+			sum += dists[i]
+		}
+		// Fewer distinct colors than clusters.
+		if sum == 0 {
+			centers = append(centers, centers[0])
+			continue
+		}
+		t := rng.Float64() * sum
+		i := 0
+		for ; i < len(pixels)-1; i++ {
+			t -= dists[i]
+			if t < 0 {
+				break
+			}
+		}
+		centers = append(centers, pixels[i])
+	}
+
+	// Lloyd's algorithm.
+	clusters := make([]int, len(pixels))
+	for iter := 0; iter < 64; iter++ {
+		changed := false
+		for i, p := range pixels {
+			if c := nearest(p, centers); c != clusters[i] || iter == 0 {
+				clusters[i], changed = c, true
+			}
+		}
+		if !changed {
+			break
+		}
+		sums := make([]lab, k)
+		counts := make([]int, k)
+		for i, p := range pixels {
+			c := clusters[i]
+			sums[c].L, sums[c].A, sums[c].B = sums[c].L+p.L, sums[c].A+p.A, sums[c].B+p.B
+			counts[c]++
+		}
+		for c := range centers {
+			// Empty clusters keep their center.
+			if counts[c] == 0 {
+				continue
+			}
+			n := float64(counts[c])
+			centers[c] = lab{L: sums[c].L / n, A: sums[c].A / n, B: sums[c].B / n}
+		}
+	}
+	return centers
+}
+
+// nearest returns the index of the center closest to the pixel.
+func nearest(p lab, centers []lab) int {
+	best, min := 0, math.Inf(1)
+	for i, c := range centers {
+		if d := p.dist(c); d < min {
+			best, min = i, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,55 @@
+package iro
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestLabRoundTrip(t *testing.T) {
+	for _, c := range []RGBA{{0, 0, 0, 1}, {1, 1, 1, 1}, {0.2, 0.6, 0.9, 1}, {1, 0.5, 0, 1}} {
+		got := toLab(c).rgba()
+		if math.Abs(got.R-c.R)+math.Abs(got.G-c.G)+math.Abs(got.B-c.B) > 1e-6 {
+			t.Errorf("%v: got %v after CIELAB", c, got)
+		}
+	}
+}
+
+func TestPalette(t *testing.T) {
+	// Vertical stripes of red, dark green and blue, and a transparent stripe
+	// which is ignored. The green is darker than the red, but after it in hue.
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 10))
+	stripes := []color.NRGBA{{255, 0, 0, 255}, {0, 100, 0, 255}, {0, 0, 255, 255}, {255, 255, 255, 0}}
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 10; y++ {
+			img.Set(x, y, stripes[x/10])
+		}
+	}
+	red, green, blue := RGBA{1, 0, 0, 1}, RGBA{0, 100.0 / 255, 0, 1}, RGBA{0, 0, 1, 1}
+	golden := []struct {
+		order Order
+		want  []RGBA
+	}{
+		{ByLuminance, []RGBA{blue, green, red}},
+		{ByHue, []RGBA{blue, red, green}},
+	}
+	for _, g := range golden {
+		got := Palette(img, 3, g.order)
+		if len(got) != len(g.want) {
+			t.Fatalf("%v: got %d colors, want %d", g.order, len(got), len(g.want))
+		}
+		for i := range got {
+			if math.Abs(got[i].R-g.want[i].R)+math.Abs(got[i].G-g.want[i].G)+math.Abs(got[i].B-g.want[i].B) > 1e-3 {
+				t.Errorf("%v: color %d is %v, want %v", g.order, i, got[i], g.want[i])
+			}
+		}
+	}
+	// More colors than the image has.
+	if got := Palette(img, 5, ByLuminance); len(got) != 5 {
+		t.Errorf("got %d colors, want 5", len(got))
+	}
+	if _, _, err := PaletteStops(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 3, ByLuminance); err == nil {
+		t.Errorf("got a gradient of a transparent image")
+	}
+}
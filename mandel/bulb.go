@@ -0,0 +1,90 @@
+package mandel
+
+import (
+	"math"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+// disk is a disk inside one of the bulbs of a fractal.
+type disk struct {
+	c  complex128 // Center.
+	r2 float64    // Squared radius.
+}
+
+// bulbs are disks inside the larger bulbs of the mandelbrot, apart from the
+// period 2 bulb which is tested exactly. Only the bulbs of the upper half-plane
+// are given, since the mandelbrot is symmetric around the real axis. The disks
+// are centered at the nuclei of the bulbs, and their radii are verified by the
+// tests.
+var bulbs = []disk{
+	// Period 4 bulb left of the period 2 bulb.
+	{complex(-1.310703, 0), 0.0567 * 0.0567},
+	// Period 3 bulb on top of the main cardioid.
+	{complex(-0.122561, 0.744862), 0.0910 * 0.0910},
+	// Period 4 bulb on the upper right of the main cardioid.
+	{complex(0.282271, 0.530061), 0.0419 * 0.0419},
+	// Period 5 bulb on the upper left of the main cardioid.
+	{complex(-0.504340, 0.562766), 0.0379 * 0.0379},
+	// Period 5 bulb on the right of the main cardioid.
+	{complex(0.379514, 0.334932), 0.0224 * 0.0224},
+}
+
+// IsInBulb returns true if the point c is in the main cardioid or one of the
+// larger bulbs of the mandelbrot.
+//
+// Credits: https://github.com/morcmarc/buddhabrot/blob/master/buddhabrot.go
+func IsInBulb(c complex128) bool {
+	x, y := real(c), math.Abs(imag(c))
+	// Main cardioid.
+	q := (x-0.25)*(x-0.25) + y*y
+	if q*(q+(x-0.25)) < 0.25*y*y {
+		return true
+	}
+	// Period 2 bulb.
+	if (x+1)*(x+1)+y*y < 0.0625 {
+		return true
+	}
+	for _, b := range bulbs {
+		dx, dy := x-real(b.c), y-imag(b.c)
+		if dx*dx+dy*dy < b.r2 {
+			return true
+		}
+	}
+	return false
+}
+
+// InMandelbrotBulb is the fast rejection test of Mandelbrot. Substituting w =
+// coef*z shows that the orbit of coef*z^2 + coef*c is bounded exactly when the
+// orbit of w^2 + coef^2*c is.
+func InMandelbrotBulb(c complex128, frac *fractal.Fractal) bool {
+	return IsInBulb(frac.Coef * frac.Coef * c)
+}
+
+// InMultibrotDisk returns the fast rejection test of the multibrot z^d + c,
+// for integer powers d > 1. It tests the largest disk around origo inside the
+// main component, where the fixed point of the function is attracting. The
+// test is disabled for functions with a coefficient other than one.
+func InMultibrotDisk(d int) func(complex128, *fractal.Fractal) bool {
+	if d < 2 {
+		return nil
+	}
+	// The boundary of the main component is c = w - w^d where |d*w^(d-1)| =
+	// 1, which is closest to origo at the distance |w| - |w|^d.
+	w := math.Pow(float64(d), -1/float64(d-1))
+	r := w - math.Pow(w, float64(d))
+	r2 := r * r
+	return func(c complex128, frac *fractal.Fractal) bool {
+		if frac.Coef != 1 {
+			return false
+		}
+		return real(c)*real(c)+imag(c)*imag(c) < r2
+	}
+}
+
+// rejected returns true if the orbit of the points (z, c) is known to stay
+// bounded by the fast rejection test of the complex function. The tests
+// describe the orbits of origo, so other starting points are never rejected.
+func rejected(z, c complex128, frac *fractal.Fractal) bool {
+	return frac.Reject != nil && z == 0 && frac.Reject(c, frac)
+}
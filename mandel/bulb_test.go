@@ -0,0 +1,110 @@
+package mandel
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+// bounded returns true if the orbit of origo under z^d + c stays bounded for
+// the given number of iterations.
+func bounded(c complex128, d, iterations int) bool {
+	var z complex128
+	for i := 0; i < iterations; i++ {
+		w := complex(1, 0)
+		for k := 0; k < d; k++ {
+			w *= z
+		}
+		z = w + c
+		if cmplx.Abs(z) > 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// circle returns n points on the circle around c with radius r.
+func circle(c complex128, r float64, n int) []complex128 {
+	ps := make([]complex128, n)
+	for k := range ps {
+		t := 2 * math.Pi * float64(k) / float64(n)
+		ps[k] = c + complex(r*math.Cos(t), r*math.Sin(t))
+	}
+	return ps
+}
+
+func TestBulbs(t *testing.T) {
+	for _, b := range bulbs {
+		for _, c := range circle(b.c, math.Sqrt(b.r2), 360) {
+			// The mirrored bulb of the lower half-plane.
+			for _, c := range []complex128{c, cmplx.Conj(c)} {
+				if !bounded(c, 2, 10000) {
+					t.Errorf("bulb around %v: orbit of %v escapes", b.c, c)
+				}
+			}
+		}
+	}
+}
+
+func TestIsInBulb(t *testing.T) {
+	// Points on a grid over the domain of the mandelbrot.
+	const n = 400
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			c := complex(-2+4*float64(i)/n, -2+4*float64(j)/n)
+			if IsInBulb(c) && !bounded(c, 2, 10000) {
+				t.Errorf("orbit of %v escapes, but is in a bulb", c)
+			}
+		}
+	}
+}
+
+func TestInMultibrotDisk(t *testing.T) {
+	frac := &fractal.Fractal{Coef: 1}
+	for d := 2; d <= 6; d++ {
+		reject := InMultibrotDisk(d)
+		// The radius of the disk, found by bisection.
+		lo, hi := 0.0, 2.0
+		for k := 0; k < 60; k++ {
+			r := (lo + hi) / 2
+			if reject(complex(r, 0), frac) {
+				lo = r
+			} else {
+				hi = r
+			}
+		}
+		for _, c := range circle(0, lo, 360) {
+			if !bounded(c, d, 10000) {
+				t.Errorf("power %d: orbit of %v escapes, but is in the disk", d, c)
+			}
+		}
+	}
+}
+
+func TestInMandelbrotBulb(t *testing.T) {
+	// The orbit of origo under coef*z^2 + coef*c.
+	orbit := func(c, coef complex128) bool {
+		var z complex128
+		for i := 0; i < 10000; i++ {
+			z = coef*z*z + coef*c
+			if cmplx.Abs(z) > 1e3 {
+				return false
+			}
+		}
+		return true
+	}
+	const n = 100
+	for _, coef := range []complex128{1, 0.5, complex(0.8, 0.6), -1.3} {
+		frac := &fractal.Fractal{Coef: coef}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				c := complex(-4+8*float64(i)/n, -4+8*float64(j)/n)
+				if InMandelbrotBulb(c, frac) && !orbit(c, coef) {
+					t.Errorf("coef %v: orbit of %v escapes, but is rejected", coef, c)
+				}
+			}
+		}
+	}
+}
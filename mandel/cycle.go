@@ -0,0 +1,75 @@
+package mandel
+
+import (
+	"math/cmplx"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+// Cycle detects periodic orbits with the strategy of a fractal. A point is
+// considered to repeat a saved point of the orbit if their distance is at most
+// the epsilon of the fractal.
+type Cycle struct {
+	Period int64 // Period of the detected cycle.
+
+	detection fractal.CycleDetection
+	eps2      float64    // Squared epsilon.
+	saved     complex128 // The point which later points are compared against.
+	savedAt   int64      // Iteration of the saved point.
+	power     int64      // Brent: iterations until the saved point is replaced.
+}
+
+// NewCycle returns a cycle detector for an orbit of the fractal. The state of
+// the orbits of stateful iterators isn't only their last point, so a repeated
+// point isn't a cycle, and detection is disabled for them.
+func NewCycle(frac *fractal.Fractal) Cycle {
+	detection := frac.CycleDetection
+	if frac.NewIterator != nil {
+		detection = fractal.NoDetection
+	}
+	return Cycle{
+		detection: detection,
+		eps2:      frac.Epsilon * frac.Epsilon,
+		// No point is close to NaN, so nothing is detected before the first
+		// point is saved.
+		saved:   cmplx.NaN(),
+		savedAt: -1,
+		power:   1,
+	}
+}
+
+// Found returns true if z, the point of iteration i, repeats a previous point
+// of the orbit. The period of the cycle is stored in the detector.
+func (cy *Cycle) Found(z complex128, i int64) bool {
+	switch cy.detection {
+	case fractal.NoDetection:
+		return false
+	case fractal.Brent:
+		if cy.near(z) {
+			cy.Period = i - cy.savedAt
+			return true
+		}
+		if i-cy.savedAt >= cy.power {
+			cy.saved, cy.savedAt = z, i
+			cy.power *= 2
+		}
+		return false
+	default:
+		// Cycle-detection (See algorithmic explanation in README.md).
+		if (i-1)&i == 0 && i > 1 {
+			cy.saved, cy.savedAt = z, i
+			return false
+		}
+		if cy.near(z) {
+			cy.Period = i - cy.savedAt
+			return true
+		}
+		return false
+	}
+}
+
+// near returns true if z is within epsilon of the saved point.
+func (cy *Cycle) near(z complex128) bool {
+	d := z - cy.saved
+	return real(d)*real(d)+imag(d)*imag(d) <= cy.eps2
+}
@@ -0,0 +1,74 @@
+package mandel
+
+import (
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+func TestCycle(t *testing.T) {
+	tests := []struct {
+		c      complex128
+		period int64
+	}{
+		// Centers of hyperbolic components, whose orbits of origo are
+		// periodic.
+		{0, 1},
+		{-1, 2},
+		{-1.7548776662466927, 3},
+		{complex(-0.12256116687665362, 0.7448617666197442), 3},
+		// Points which escape, some of them slowly.
+		{0.5, 0},
+		{0.26, 0},
+		{complex(-0.75, 0.05), 0},
+	}
+	for _, detection := range []fractal.CycleDetection{fractal.Backoff, fractal.Brent} {
+		for _, eps := range []float64{0, 1e-12} {
+			frac := &fractal.Fractal{
+				Iterations:     10000,
+				Coef:           complex(1, 0),
+				Bailout:        4,
+				Func:           Mandelbrot,
+				CycleDetection: detection,
+				Epsilon:        eps,
+			}
+			orbit := fractal.NewOrbit(frac.Iterations)
+			for _, test := range tests {
+				Escaped(0, test.c, orbit, frac)
+				if test.period == 0 {
+					if orbit.Outcome != fractal.Escaped {
+						t.Errorf("%v, epsilon %g: orbit of %v has the outcome %v, want %v", detection, eps, test.c, orbit.Outcome, fractal.Escaped)
+					}
+					continue
+				}
+				if orbit.Outcome != fractal.Cycle || orbit.Period != test.period {
+					t.Errorf("%v, epsilon %g: orbit of %v has the outcome %v with period %d, want period %d", detection, eps, test.c, orbit.Outcome, orbit.Period, test.period)
+				}
+			}
+		}
+	}
+}
+
+func TestIsCycle(t *testing.T) {
+	for _, test := range []struct {
+		c     complex128
+		cycle bool
+	}{
+		{-1, true},
+		{complex(-0.12256116687665362, 0.7448617666197442), true},
+		{0.26, false},
+	} {
+		var z, bfract complex128
+		found := false
+		for i := int64(0); i < 1000 && !IsOutside(z, 4); i++ {
+			z = Mandelbrot(z, test.c, 1)
+			if IsCycle(z, &bfract, i) {
+				found = true
+				break
+			}
+		}
+		if found != test.cycle {
+			t.Errorf("orbit of %v: got cycle %v, want %v", test.c, found, test.cycle)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package mandel
+
+import (
+	"github.com/karlek/wasabi/dd"
+	"github.com/karlek/wasabi/fractal"
+)
+
+// one is the coefficient of functions without a coefficient.
+var one = dd.Cmplx(1)
+
+// MandelbrotDD is Mandelbrot in double-double precision.
+func MandelbrotDD(z, c, coef dd.Complex) dd.Complex {
+	if coef == one {
+		return z.Sqr().Add(c)
+	}
+	return coef.Mul(z.Sqr()).Add(coef.Mul(c))
+}
+
+// MultibrotDD returns Multibrot in double-double precision for the power d, or
+// nil unless d is a positive integer.
+func MultibrotDD(d float64) func(z, c, coef dd.Complex) dd.Complex {
+	n := int(d)
+	if float64(n) != d || n < 1 {
+		return nil
+	}
+	if n == 2 {
+		return MandelbrotDD
+	}
+	return func(z, c, coef dd.Complex) dd.Complex {
+		w := z
+		for k := 1; k < n; k++ {
+			w = w.Mul(z)
+		}
+		if coef == one {
+			return w.Add(c)
+		}
+		return coef.Mul(w).Add(coef.Mul(c))
+	}
+}
+
+// iterateDD iterates the orbit of the points (z, c) with the double-double
+// function of the fractal, and stores the high and low parts of its points in
+// the orbit. It returns the number of iterations, and leaves the outcome of
+// the orbit in it. The escaping point isn't stored, like the registering
+// functions of complex128.
+func iterateDD(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) (i int64) {
+	if orbit.Lows == nil {
+		orbit.Lows = make([]complex128, len(orbit.Points))
+	}
+	// Detector of periodic orbits, which compares the high parts.
+	cycle := NewCycle(frac)
+
+	zz, cc, coef := dd.Cmplx(z), dd.Cmplx(c), dd.Cmplx(frac.Coef)
+	for i = 0; i < frac.Iterations; i++ {
+		zz = frac.FuncDD(zz, cc, coef)
+		if cycle.Found(zz.Hi(), i) {
+			orbit.Outcome, orbit.Period = fractal.Cycle, cycle.Period
+			return i
+		}
+		if zz.Abs2() >= frac.Bailout {
+			orbit.Outcome = fractal.Escaped
+			return i
+		}
+		orbit.Points[i], orbit.Lows[i] = zz.Hi(), zz.Lo()
+	}
+	orbit.Outcome = fractal.Converged
+	return i
+}
+
+// escapedDD is Escaped in double-double precision.
+func escapedDD(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
+	i := iterateDD(z, c, orbit, frac)
+	if orbit.Outcome != fractal.Escaped {
+		return -1
+	}
+	return i
+}
+
+// convergedDD is Converged in double-double precision.
+func convergedDD(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
+	i := iterateDD(z, c, orbit, frac)
+	if orbit.Outcome == fractal.Escaped {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,102 @@
+package mandel
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+// MandelbrotDC returns the derivative with respect to c of the point after z
+// in an orbit of Mandelbrot, where dz is the derivative of z.
+func MandelbrotDC(z, dz, _, coef complex128) complex128 {
+	return coef * (2*z*dz + 1)
+}
+
+// MultibrotDC returns the derivative with respect to c of Multibrot with the
+// power d.
+func MultibrotDC(d float64) func(z, dz, c, coef complex128) complex128 {
+	if d == 2 {
+		return MandelbrotDC
+	}
+	n, p := int(d), complex(d-1, 0)
+	return func(z, dz, _, coef complex128) complex128 {
+		// Origo is kept at zero by the negative and fractional powers.
+		if z == 0 {
+			return coef
+		}
+		var w complex128
+		if float64(n) == d && n > 1 && n <= MaxFastPower {
+			w = Pow(z, n-1)
+		} else {
+			w = cmplx.Pow(z, p)
+		}
+		return coef * (complex(d, 0)*w*dz + 1)
+	}
+}
+
+// MandelbrotDZ returns the derivative of Mandelbrot with respect to z.
+func MandelbrotDZ(z, _, coef complex128) complex128 {
+	return 2 * coef * z
+}
+
+// MultibrotDZ returns the derivative of Multibrot with the power d with respect
+// to z.
+func MultibrotDZ(d float64) func(z, c, coef complex128) complex128 {
+	if d == 2 {
+		return MandelbrotDZ
+	}
+	n, p := int(d), complex(d-1, 0)
+	return func(z, _, coef complex128) complex128 {
+		// Origo is kept at zero by the negative and fractional powers.
+		if z == 0 {
+			return 0
+		}
+		if float64(n) == d && n > 1 && n <= MaxFastPower {
+			return coef * complex(d, 0) * Pow(z, n-1)
+		}
+		return coef * complex(d, 0) * cmplx.Pow(z, p)
+	}
+}
+
+// EscapedDerivative is EscapedLast which also returns the derivative dz/dc of
+// the first point outside the bailout, from which the distance to the
+// fractal is estimated. The complex function of the fractal must have a
+// derivative.
+func EscapedDerivative(z, c complex128, frac *fractal.Fractal) (complex128, complex128, int64) {
+	// We ignore all values that we know are in the bulb, and will therefore
+	// converge.
+	if rejected(z, c, frac) {
+		return z, 0, -1
+	}
+
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
+
+	// See if the complex function diverges before we reach our iteration count.
+	var dz complex128
+	step := frac.Step()
+	var i int64
+	for i = 0; i < frac.Iterations; i++ {
+		dz = frac.FuncDC(z, dz, c, frac.Coef)
+		z = step(z, c, frac.Coef)
+		if cycle.Found(z, i) {
+			return z, dz, -1
+		}
+		if IsOutside(z, frac.Bailout) {
+			return z, dz, i
+		}
+	}
+
+	// This point converges; assumed under the number of iterations.
+	return z, dz, -1
+}
+
+// Distance returns the exterior distance estimate of an escaped point c to
+// the boundary of the mandelbrot, from the first point z of its orbit outside
+// the bailout and the derivative dz/dc. The true distance is between a quarter
+// and the full estimate, which improves with larger bailouts.
+func Distance(z, dz complex128) float64 {
+	r := cmplx.Abs(z)
+	return 2 * r * math.Log(r) / cmplx.Abs(dz)
+}
@@ -0,0 +1,37 @@
+package mandel
+
+import (
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+func TestDistance(t *testing.T) {
+	frac := &fractal.Fractal{
+		Iterations: 1000,
+		Bailout:    1e6,
+		Coef:       complex(1, 0),
+		Func:       Mandelbrot,
+		FuncDC:     MandelbrotDC,
+	}
+	golden := []struct {
+		c    complex128
+		dist float64
+	}{
+		// The closest points of the mandelbrot are the cusp 1/4 and the tip
+		// of the antenna -2.
+		{complex(1, 0), 0.75},
+		{complex(-2.5, 0), 0.5},
+	}
+	for _, g := range golden {
+		last, dz, i := EscapedDerivative(0, g.c, frac)
+		if i == -1 {
+			t.Errorf("%v: didn't escape", g.c)
+			continue
+		}
+		// The true distance is between a quarter and the full estimate.
+		if d := Distance(last, dz); d < g.dist || d/4 > g.dist {
+			t.Errorf("%v: estimated distance %g, true distance %g", g.c, d, g.dist)
+		}
+	}
+}
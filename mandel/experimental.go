@@ -1,73 +1,108 @@
 package mandel
 
 import (
+	"math/cmplx"
+
 	"github.com/karlek/wasabi/fractal"
 )
 
-func FieldLines(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
-	zp := complex(0, 0)
-	g := 10000.0
-	// We ignore all values that we know are in the bulb, and will therefore
-	// converge.
-	if IsInBulb(c) {
-		return -1
-	}
-
-	// Saved value for cycle-detection.
-	var bfract complex128
+// FieldLinesGrowth is the default growth g of the field lines.
+const FieldLinesGrowth = 10000
 
-	// See if the complex function diverges before we reach our iteration count.
-	var i int64
-	for i = 0; i < frac.Iterations; i++ {
-		z = frac.Func(z, c, frac.Coef)
-		if IsCycle(z, &bfract, i) {
+// FieldLines returns a registrer of the orbits which cross a field line, where
+// both parts of a point grow more than g times from the previous point. Lower
+// values of g draw thicker field lines.
+func FieldLines(g float64) Registrer {
+	return func(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
+		// We ignore all values that we know are in the bulb, and will therefore
+		// converge.
+		if rejected(z, c, frac) {
+			orbit.Outcome = fractal.InBulb
 			return -1
 		}
 
-		// This point diverges, so we all the preceeding points are interesting
-		// and will be registered.
-		// if x, y := real(z), imag(z); x*x+y*y >= frac.Bailout {
-		if real, imag, rp, ip := real(z), imag(z), real(zp), imag(zp); real/rp > g && imag/ip > g {
-			return i
-		}
-		// }
+		// Detector of periodic orbits.
+		cycle := NewCycle(frac)
 
-		orbit.Points[i] = z
-		zp = z
+		// See if the orbit crosses a field line before we reach our iteration
+		// count.
+		step := frac.Step()
+		zp := z
+		var i int64
+		for i = 0; i < frac.Iterations; i++ {
+			z = step(z, c, frac.Coef)
+			if cycle.Found(z, i) {
+				orbit.Outcome, orbit.Period = fractal.Cycle, cycle.Period
+				return -1
+			}
+
+			// This point crosses a field line, so all the preceeding points are
+			// interesting and will be registered.
+			if fieldLine(z, zp, g) {
+				orbit.Outcome = fractal.Escaped
+				return i
+			}
+			// The orbit diverged without crossing a field line.
+			if diverged(z) {
+				orbit.Outcome = fractal.Escaped
+				return -1
+			}
+			orbit.Points[i] = z
+			zp = z
+		}
+		// This point converges; assumed under the number of iterations.
+		orbit.Outcome = fractal.Converged
+		return -1
 	}
-	// This point converges; assumed under the number of iterations.
-	return -1
 }
 
+// FieldLinesEscapes returns the point and the number of iterations where the
+// orbit crosses a field line of the growth g, or -1 if it doesn't. Unlike
+// FieldLines, points in the first quadrant are compared against the conjugate
+// of the previous point, which draws the external rays of the coloring of
+// lotus.
 func FieldLinesEscapes(z, c complex128, frac *fractal.Fractal, g float64) (complex128, int64) {
-	zp := complex(0, 0)
-
-	// Saved value for cycle-detection.
-	var bfract complex128
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
 
-	// See if the complex function diverges before we reach our iteration count.
+	// See if the orbit crosses a field line before we reach our iteration count.
+	step := frac.Step()
+	zp := z
 	var i int64
 	for i = 0; i < frac.Iterations; i++ {
-		z = frac.Func(z, c, frac.Coef)
-		if IsCycle(z, &bfract, i) {
+		z = step(z, c, frac.Coef)
+		if cycle.Found(z, i) {
 			return z, -1
 		}
-
-		// This point diverges, so we all the preceeding points are interesting
-		// and will be registered.
-		real, imag, rp, ip := real(z), imag(z), real(zp), imag(zp)
-		if real > 0 && imag > 0 {
-			ip = -ip
+		prev := zp
+		if real(z) > 0 && imag(z) > 0 {
+			prev = cmplx.Conj(zp)
 		}
-		if real/rp > g && imag/ip > g {
+		if fieldLine(z, prev, g) {
 			return z, i
 		}
-		// Only boundary with values for g == 0.1
-		// if real, imag, rp, ip := real(z), imag(z), real(zp), imag(zp); math.Abs(real/rp) < g && math.Abs(imag/ip) < g {
-		// 	return i
-		// }
+		if diverged(z) {
+			return z, -1
+		}
 		zp = z
 	}
 	// This point converges; assumed under the number of iterations.
 	return z, -1
 }
+
+// fieldLine returns true if both parts of the point z grew more than g times
+// from the previous point zp. It returns false if a part of zp is zero, e.g. on
+// the first iteration from origo, where the growth is undefined.
+func fieldLine(z, zp complex128, g float64) bool {
+	rp, ip := real(zp), imag(zp)
+	if rp == 0 || ip == 0 {
+		return false
+	}
+	return real(z)/rp > g && imag(z)/ip > g
+}
+
+// diverged returns true if the point overflowed, after which no field line can
+// be crossed.
+func diverged(z complex128) bool {
+	return cmplx.IsInf(z) || cmplx.IsNaN(z)
+}
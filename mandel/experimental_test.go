@@ -0,0 +1,46 @@
+package mandel
+
+import (
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+func TestFieldLines(t *testing.T) {
+	frac := &fractal.Fractal{
+		Iterations: 100,
+		Coef:       complex(1, 0),
+		Func:       Mandelbrot,
+	}
+	// The first point from origo is c itself, which grew infinitely from the
+	// previous point.
+	c := complex(1, 1)
+	if _, n := FieldLinesEscapes(0, c, frac, 10); n == 0 {
+		t.Errorf("orbit of %v crossed a field line on the first iteration", c)
+	}
+	// The orbit of a point outside the mandelbrot crosses a field line before
+	// it overflows.
+	for _, test := range []struct {
+		c       complex128
+		escapes int64 // Iterations of FieldLinesEscapes, which flips the first quadrant.
+	}{
+		{complex(1, -1), 6},
+		{complex(1, 1), -1},
+	} {
+		orbit := fractal.NewOrbit(frac.Iterations)
+		if n := FieldLines(FieldLinesGrowth)(0, test.c, orbit, frac); n <= 0 || orbit.Outcome != fractal.Escaped {
+			t.Errorf("orbit of %v registered %d points with the outcome %v", test.c, n, orbit.Outcome)
+		}
+		if _, n := FieldLinesEscapes(0, test.c, frac, FieldLinesGrowth); n != test.escapes {
+			t.Errorf("orbit of %v crossed a field line after %d iterations, want %d", test.c, n, test.escapes)
+		}
+	}
+	for _, zp := range []complex128{0, 1, complex(0, 1)} {
+		if fieldLine(complex(5, 5), zp, 1) {
+			t.Errorf("%v crossed a field line from %v", complex(5, 5), zp)
+		}
+	}
+	if !fieldLine(complex(5, 5), complex(0.1, 0.1), 10) {
+		t.Errorf("%v didn't cross a field line from %v", complex(5, 5), complex(0.1, 0.1))
+	}
+}
@@ -0,0 +1,21 @@
+package mandel
+
+// The magnet fractals only depend on the last point of their orbits, unlike
+// the phoenix, so they're plain complex functions which need no iterators.
+
+// Magnet1 is the first magnet fractal, from the renormalization of models of
+// magnetism, whose orbits converge to 1 in large regions:
+//
+//	z' = ((z^2 + c - 1) / (2z + c - 2))^2
+func Magnet1(z, c, coef complex128) complex128 {
+	w := (z*z + c - 1) / (2*z + c - 2)
+	return coef * w * w
+}
+
+// Magnet2 is the second magnet fractal:
+//
+//	z' = ((z^3 + 3(c-1)z + (c-1)(c-2)) / (3z^2 + 3(c-2)z + (c-1)(c-2) + 1))^2
+func Magnet2(z, c, coef complex128) complex128 {
+	w := (z*z*z + 3*(c-1)*z + (c-1)*(c-2)) / (3*z*z + 3*(c-2)*z + (c-1)*(c-2) + 1)
+	return coef * w * w
+}
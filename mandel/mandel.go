@@ -3,8 +3,8 @@ package mandel
 import (
 	"math"
 	"math/cmplx"
-	"reflect"
 
+	"github.com/karlek/wasabi/dd"
 	"github.com/karlek/wasabi/fractal"
 )
 
@@ -12,40 +12,16 @@ import (
 // for a specific fractal.
 type Registrer func(complex128, complex128, *fractal.Orbit, *fractal.Fractal) int64
 
-// IsInBulb returns true if the point c is in one of the larger bulb's of the
-// mandelbrot.
+// IsCycle uses exponential back-off to detect orbits which repeat the saved
+// point bfract exactly. The points of the iterations which are powers of two
+// are saved in bfract.
 //
-// Credits: https://github.com/morcmarc/buddhabrot/blob/master/buddhabrot.go
-func IsInBulb(c complex128) bool {
-	Cr, Ci := real(c), imag(c)
-	// Main cardioid
-	if !(((Cr-0.25)*(Cr-0.25)+(Ci*Ci))*(((Cr-0.25)*(Cr-0.25)+(Ci*Ci))+(Cr-0.25)) < 0.25*Ci*Ci) {
-		// 2nd order period bulb
-		if !((Cr+1.0)*(Cr+1.0)+(Ci*Ci) < 0.0625) {
-			// smaller bulb left of the period-2 bulb
-			if !((((Cr + 1.309) * (Cr + 1.309)) + Ci*Ci) < 0.00345) {
-				// smaller bulb bottom of the main cardioid
-				if !((((Cr + 0.125) * (Cr + 0.125)) + (Ci-0.744)*(Ci-0.744)) < 0.0088) {
-					// smaller bulb top of the main cardioid
-					if !((((Cr + 0.125) * (Cr + 0.125)) + (Ci+0.744)*(Ci+0.744)) < 0.0088) {
-						return false
-					}
-				}
-			}
-		}
-	}
-	return true
-}
-
-// IsCycle uses exponential back-off for cycle detection.
+// Deprecated: Use Cycle, which also handles tolerances and other strategies.
 func IsCycle(z complex128, bfract *complex128, i int64) bool {
-	// Cycle-detection (See algorithmic explanation in README.md).
-	if (i-1)&i == 0 && i > 1 {
-		(*bfract) = z
-	} else if z == *bfract {
-		return true
-	}
-	return false
+	cy := Cycle{detection: fractal.Backoff, saved: *bfract}
+	found := cy.Found(z, i)
+	*bfract = cy.saved
+	return found
 }
 
 // IsOutside checks wheter the point is outside the chosen domain. Bailout
@@ -67,52 +43,67 @@ func abs(c complex128) float64 {
 func Escaped(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
 	// We ignore all values that we know are in the bulb, and will therefore
 	// converge.
-	if IsInBulb(c) {
+	if rejected(z, c, frac) {
+		orbit.Outcome = fractal.InBulb
 		return -1
 	}
+	if frac.Precision == fractal.DoubleDouble {
+		return escapedDD(z, c, orbit, frac)
+	}
 
-	// Saved value for cycle-detection.
-	var bfract complex128
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
 
 	// See if the complex function diverges before we reach our iteration count.
+	step := frac.Step()
 	var i int64
 	for i = 0; i < frac.Iterations; i++ {
-		z = frac.Func(z, c, frac.Coef)
-		if IsCycle(z, &bfract, i) {
+		z = step(z, c, frac.Coef)
+		if cycle.Found(z, i) {
+			orbit.Outcome, orbit.Period = fractal.Cycle, cycle.Period
 			return -1
 		}
 
 		// This point diverges, which means all the preceeding points are interesting
 		// and will be registered.
 		if IsOutside(z, frac.Bailout) {
+			orbit.Outcome = fractal.Escaped
 			return i
 		}
 		orbit.Points[i] = z
 	}
 	// This point converges; assumed under the number of iterations.
+	orbit.Outcome = fractal.Converged
 	return -1
 }
 
 // Converged returns all points in the domain of the complex function before
 // diverging.
 func Converged(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
-	if IsInBulb(c) {
+	if rejected(z, c, frac) {
+		orbit.Outcome = fractal.InBulb
 		return -1
 	}
-	// Saved value for cycle-detection.
-	var bfract complex128
+	if frac.Precision == fractal.DoubleDouble {
+		return convergedDD(z, c, orbit, frac)
+	}
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
 
 	// See if the complex function diverges before we reach our iteration count.
+	step := frac.Step()
 	var i int64
 	for i = 0; i < frac.Iterations; i++ {
-		z = frac.Func(z, c, frac.Coef)
-		if IsCycle(z, &bfract, i) {
+		z = step(z, c, frac.Coef)
+		if cycle.Found(z, i) {
+			orbit.Outcome, orbit.Period = fractal.Cycle, cycle.Period
 			return i
 		}
 
 		// This point diverges. Since it's the anti-buddhabrot, we are not
 		// interested in these points.
 		if IsOutside(z, frac.Bailout) {
+			orbit.Outcome = fractal.Escaped
 			return -1
 		}
 
@@ -120,25 +111,32 @@ func Converged(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int
 	}
 	// This point converges; assumed under the number of iterations. Since it's
 	// the anti-buddhabrot we register the orbit.
+	orbit.Outcome = fractal.Converged
 	return i
 }
 
 // Primitive returns all points in the domain of the complex function
 // diverging.
 func Primitive(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) (i int64) {
-	// Saved value for cycle-detection.
-	var bfract complex128
+	if frac.Precision == fractal.DoubleDouble {
+		return iterateDD(z, c, orbit, frac)
+	}
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
 
 	// See if the complex function diverges before we reach our iteration count.
+	step := frac.Step()
 	for i = 0; i < frac.Iterations; i++ {
-		z = frac.Func(z, c, frac.Coef)
-		if IsCycle(z, &bfract, i) {
+		z = step(z, c, frac.Coef)
+		if cycle.Found(z, i) {
+			orbit.Outcome, orbit.Period = fractal.Cycle, cycle.Period
 			return i
 		}
 
 		// This point diverges. Since it's the primitive brot we register the
 		// orbit.
 		if IsOutside(z, frac.Bailout) {
+			orbit.Outcome = fractal.Escaped
 			return i
 		}
 		// Save the point.
@@ -146,6 +144,7 @@ func Primitive(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) (i
 	}
 	// This point converges; assumed under the number of iterations.
 	// Since it's the primitive brot we register the orbit.
+	orbit.Outcome = fractal.Converged
 	return i
 }
 
@@ -155,18 +154,19 @@ func Primitive(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) (i
 func EscapedLast(z, c complex128, frac *fractal.Fractal) (complex128, int64) {
 	// We ignore all values that we know are in the bulb, and will therefore
 	// converge.
-	if IsInBulb(c) && equal(frac.Func, Mandelbrot) {
+	if rejected(z, c, frac) {
 		return z, -1
 	}
 
-	// Saved value for cycle-detection.
-	var bfract complex128
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
 
 	// See if the complex function diverges before we reach our iteration count.
+	step := frac.Step()
 	var i int64
 	for i = 0; i < frac.Iterations; i++ {
-		z = frac.Func(z, c, frac.Coef)
-		if IsCycle(z, &bfract, i) {
+		z = step(z, c, frac.Coef)
+		if cycle.Found(z, i) {
 			return z, -1
 		}
 
@@ -181,6 +181,33 @@ func EscapedLast(z, c complex128, frac *fractal.Fractal) (complex128, int64) {
 	return z, -1
 }
 
+// Function is a complex function to explore, together with its fast rejection
+// test of points c whose orbits are known to stay bounded, its default squared
+// bailout radius, its double-double variant and its derivatives with respect to
+// c and z. The test, variant and derivatives are nil if the function has none.
+type Function struct {
+	F       func(z, c, coef complex128) complex128
+	Reject  func(complex128, *fractal.Fractal) bool
+	Bailout float64
+	DD      func(z, c, coef dd.Complex) dd.Complex
+	DC      func(z, dz, c, coef complex128) complex128
+	DZ      func(z, c, coef complex128) complex128 // Used by Newton's method.
+	// NewIterator creates the iterators of functions which depend on earlier
+	// points of their orbits, and replaces F.
+	NewIterator func() fractal.Iterator
+}
+
+// Functions are the complex functions by name. The multibrot depends on its
+// power, and is created by NewMultibrot.
+var Functions = map[string]Function{
+	"mandelbrot":  {F: Mandelbrot, Reject: InMandelbrotBulb, Bailout: 4, DD: MandelbrotDD, DC: MandelbrotDC, DZ: MandelbrotDZ},
+	"burningship": {F: BurningShip, Bailout: 4},
+	"b1":          {F: B1, Bailout: 4},
+	"b2":          {F: B2, Bailout: 4},
+	"magnet1":     {F: Magnet1, Bailout: 100},
+	"magnet2":     {F: Magnet2, Bailout: 100},
+}
+
 func Mandelbrot(z, c, coef complex128) complex128 {
 	return coef*z*z + coef*c
 }
@@ -210,9 +237,3 @@ func B2(z, c, _ complex128) complex128 {
 	tmp := z*z + c
 	return complex(imag(tmp)-real(tmp), real(tmp)*imag(tmp))
 }
-
-func equal(a, b func(complex128, complex128, complex128) complex128) bool {
-	f1 := reflect.ValueOf(a).Pointer()
-	f2 := reflect.ValueOf(b).Pointer()
-	return f1 == f2
-}
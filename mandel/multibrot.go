@@ -0,0 +1,88 @@
+package mandel
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// MaxFastPower is the largest integer power computed by repeated
+// multiplication instead of cmplx.Pow.
+const MaxFastPower = 64
+
+// Multibrot returns the complex function coef*z^d + coef*c of the multibrot
+// with the power d, which may be fractional or negative. Small integer powers
+// are computed by repeated multiplication, which is considerably faster and
+// more exact than cmplx.Pow. Fractional powers use the principal branch.
+//
+// The orbit of origo is undefined for negative powers, so zero is kept at zero
+// and the orbit continues from c.
+func Multibrot(d float64) func(z, c, coef complex128) complex128 {
+	if d == 2 {
+		return Mandelbrot
+	}
+	if n := int(d); float64(n) == d && n != 0 && math.Abs(d) <= MaxFastPower {
+		if n > 0 {
+			return func(z, c, coef complex128) complex128 {
+				return coef*Pow(z, n) + coef*c
+			}
+		}
+		return func(z, c, coef complex128) complex128 {
+			if z == 0 {
+				return coef * c
+			}
+			return coef/Pow(z, -n) + coef*c
+		}
+	}
+	p := complex(d, 0)
+	return func(z, c, coef complex128) complex128 {
+		if z == 0 {
+			return coef * c
+		}
+		return coef*cmplx.Pow(z, p) + coef*c
+	}
+}
+
+// Pow returns z^n for integers n by binary exponentiation.
+func Pow(z complex128, n int) complex128 {
+	inv := n < 0
+	if inv {
+		n = -n
+	}
+	w := complex(1, 0)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			w *= z
+		}
+		z *= z
+	}
+	if inv {
+		return 1 / w
+	}
+	return w
+}
+
+// MultibrotBailout returns the squared bailout radius of the multibrot with
+// the power d. For powers larger than one every orbit which leaves the radius
+// max(2, 2^(1/(d-1))) escapes, while the radius is a compromise for smaller
+// and negative powers, whose orbits don't escape to infinity.
+func MultibrotBailout(d float64) float64 {
+	const fallback = 100
+	if d <= 1 {
+		return fallback
+	}
+	r := math.Max(2, math.Pow(2, 1/(d-1)))
+	return math.Min(r*r, 1e4)
+}
+
+// NewMultibrot returns the multibrot with the power d, together with its fast
+// rejection test, default bailout, double-double variant and derivative.
+func NewMultibrot(d float64) Function {
+	f := Function{F: Multibrot(d), Bailout: MultibrotBailout(d), DD: MultibrotDD(d), DC: MultibrotDC(d), DZ: MultibrotDZ(d)}
+	switch n := int(d); {
+	case d == 2:
+		f.Reject = InMandelbrotBulb
+	case float64(n) == d && n > 2:
+		f.Reject = InMultibrotDisk(n)
+	}
+	return f
+}
@@ -0,0 +1,24 @@
+package mandel
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestMultibrot(t *testing.T) {
+	zs := []complex128{complex(0.3, -0.2), complex(-1.1, 0.7), complex(2, 1e-3)}
+	c, coef := complex(-0.4, 0.6), complex(0.9, 0.1)
+	for _, d := range []float64{-7, -2, -1, 1, 2, 3, 5, 8, 16, 2.5, -1.5} {
+		f := Multibrot(d)
+		for _, z := range zs {
+			want := coef*cmplx.Pow(z, complex(d, 0)) + coef*c
+			if got := f(z, c, coef); cmplx.Abs(got-want) > 1e-9*cmplx.Abs(want) {
+				t.Errorf("power %v: f(%v) = %v, want %v", d, z, got, want)
+			}
+		}
+		// The orbit of origo continues from c for negative powers.
+		if got := f(0, c, coef); d < 0 && got != coef*c {
+			t.Errorf("power %v: f(0) = %v, want %v", d, got, coef*c)
+		}
+	}
+}
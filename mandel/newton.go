@@ -0,0 +1,120 @@
+package mandel
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+// newtonEpsilon is the squared length of a step of Newton's method under which
+// the orbit has converged to a root.
+const newtonEpsilon = 1e-18
+
+// rootTolerance is the largest distance between a converged orbit and the root
+// of its basin.
+const rootTolerance = 1e-6
+
+// NewtonStep returns the complex function of Newton's method for the roots of
+// f in z, where df is the derivative of f with respect to z:
+//
+//	z - coef * f(z) / f'(z)
+//
+// The coefficient relaxes the steps, and f and df are evaluated with the
+// coefficient one.
+func NewtonStep(f, df func(z, c, coef complex128) complex128) func(z, c, coef complex128) complex128 {
+	return func(z, c, coef complex128) complex128 {
+		return z - coef*f(z, c, 1)/df(z, c, 1)
+	}
+}
+
+// Newton returns all points of the orbits of Newton's method which converge to
+// a root, and classifies the root among the roots of the fractal, see Basin.
+// The complex function of the fractal must be a step of Newton's method, see
+// NewtonStep. Orbits which diverge, cycle or don't converge under the
+// iterations are discarded.
+func Newton(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
+
+	step := frac.Step()
+	var i int64
+	for i = 0; i < frac.Iterations; i++ {
+		next := step(z, c, frac.Coef)
+		// The orbit hit a critical point of the function, where the step is
+		// infinite.
+		if cmplx.IsNaN(next) || cmplx.IsInf(next) {
+			orbit.Outcome = fractal.Escaped
+			return -1
+		}
+		orbit.Points[i] = next
+		if abs(next-z) < newtonEpsilon {
+			orbit.Outcome, orbit.Root = fractal.Root, Basin(next, frac.Roots)
+			return i + 1
+		}
+		if cycle.Found(next, i) {
+			orbit.Outcome, orbit.Period = fractal.Cycle, cycle.Period
+			return -1
+		}
+		z = next
+	}
+	orbit.Outcome = fractal.Converged
+	return -1
+}
+
+// NewtonRoot iterates Newton's method from z, like Newton, and returns the
+// root it converged to and the number of iterations, or -1 if it didn't
+// converge.
+func NewtonRoot(z, c complex128, frac *fractal.Fractal) (complex128, int64) {
+	step := frac.Step()
+	var i int64
+	for i = 0; i < frac.Iterations; i++ {
+		next := step(z, c, frac.Coef)
+		if cmplx.IsNaN(next) || cmplx.IsInf(next) {
+			return next, -1
+		}
+		if abs(next-z) < newtonEpsilon {
+			return next, i
+		}
+		z = next
+	}
+	return z, -1
+}
+
+// NewtonRoots returns the distinct roots which Newton's method converges to
+// from a grid of starting points over [-2, 2] x [-2, 2], sorted by their real
+// and then imaginary parts. The indices of the roots number the basins of
+// attraction, see Basin.
+func NewtonRoots(c complex128, frac *fractal.Fractal) []complex128 {
+	const n = 64
+	var roots []complex128
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			z := complex(4*(float64(x)+0.5)/n-2, 4*(float64(y)+0.5)/n-2)
+			root, i := NewtonRoot(z, c, frac)
+			if i == -1 || Basin(root, roots) != -1 {
+				continue
+			}
+			roots = append(roots, root)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		if math.Abs(real(roots[i])-real(roots[j])) > rootTolerance {
+			return real(roots[i]) < real(roots[j])
+		}
+		return imag(roots[i]) < imag(roots[j])
+	})
+	return roots
+}
+
+// Basin returns the index of the root of roots which z has converged to, or -1
+// if it's close to none of them.
+func Basin(z complex128, roots []complex128) int {
+	for i, root := range roots {
+		if cmplx.Abs(z-root) < rootTolerance {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,51 @@
+package mandel
+
+import (
+	"math/cmplx"
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+func TestNewton(t *testing.T) {
+	f := func(z, _, _ complex128) complex128 { return z*z*z - 1 }
+	df := func(z, _, _ complex128) complex128 { return 3 * z * z }
+	frac := &fractal.Fractal{
+		Iterations: 100,
+		Coef:       complex(1, 0),
+		Func:       NewtonStep(f, df),
+	}
+	roots := NewtonRoots(0, frac)
+	want := []complex128{cmplx.Rect(1, -2*cmplx.Phase(-1)/3), cmplx.Rect(1, 2*cmplx.Phase(-1)/3), 1}
+	if len(roots) != len(want) {
+		t.Fatalf("got the roots %v, want %v", roots, want)
+	}
+	for i := range want {
+		if Basin(want[i], roots) != i {
+			t.Errorf("root %d is %v, want %v", i, roots[i], want[i])
+		}
+	}
+
+	frac.Roots = roots
+	orbit := fractal.NewOrbit(frac.Iterations)
+	for _, test := range []struct {
+		z    complex128
+		root int
+	}{
+		{complex(2, 0.1), 2},
+		{complex(-1, -1), 0},
+		{complex(-1, 1), 1},
+	} {
+		n := Newton(test.z, 0, orbit, frac)
+		if n == -1 || orbit.Outcome != fractal.Root {
+			t.Fatalf("orbit of Newton's method from %v didn't converge, outcome %v", test.z, orbit.Outcome)
+		}
+		if orbit.Root != test.root {
+			t.Errorf("orbit from %v converged to %v, the root %d, want the root %d", test.z, orbit.Points[n-1], orbit.Root, test.root)
+		}
+	}
+	// Zero is a critical point, where the step is infinite.
+	if n := Newton(0, 0, orbit, frac); n != -1 {
+		t.Errorf("orbit from the critical point was registered with %d points", n)
+	}
+}
@@ -0,0 +1,27 @@
+package mandel
+
+import "github.com/karlek/wasabi/fractal"
+
+// NewPhoenix returns a function which creates the iterators of the phoenix
+// fractal with the parameter p, whose next point depends on the point before
+// the last:
+//
+//	z_{n+1} = coef*z_n^2 + coef*c + p*z_{n-1}
+func NewPhoenix(p complex128) func() fractal.Iterator {
+	return func() fractal.Iterator {
+		return &phoenix{p: p}
+	}
+}
+
+// phoenix is the iterator of an orbit of the phoenix fractal.
+type phoenix struct {
+	p    complex128
+	prev complex128 // The point before the last, which starts at zero.
+}
+
+// Next returns the point after z in the orbit of c.
+func (ph *phoenix) Next(z, c, coef complex128) complex128 {
+	next := coef*z*z + coef*c + ph.p*ph.prev
+	ph.prev = z
+	return next
+}
@@ -0,0 +1,44 @@
+package mandel
+
+import (
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+func TestPhoenix(t *testing.T) {
+	frac := &fractal.Fractal{
+		Iterations:  50,
+		Coef:        complex(1, 0),
+		Bailout:     4,
+		NewIterator: NewPhoenix(complex(-0.5, 0)),
+	}
+	c := complex(0.3, 0.4)
+	orbit := fractal.NewOrbit(frac.Iterations)
+	n := Escaped(0, c, orbit, frac)
+
+	// The recurrence by hand.
+	var prev, z complex128
+	var want []complex128
+	for i := int64(0); i < frac.Iterations; i++ {
+		prev, z = z, z*z+c-0.5*prev
+		// The escaped point isn't part of the orbit.
+		if real(z)*real(z)+imag(z)*imag(z) > frac.Bailout {
+			break
+		}
+		want = append(want, z)
+	}
+	if n != int64(len(want)) {
+		t.Fatalf("orbit escaped after %d points, want %d", n, len(want))
+	}
+	for i := range want {
+		if orbit.Points[i] != want[i] {
+			t.Errorf("point %d is %v, want %v", i, orbit.Points[i], want[i])
+		}
+	}
+
+	// Each orbit starts from a new state.
+	if m := Escaped(0, c, orbit, frac); m != n {
+		t.Errorf("second orbit escaped after %d points, want %d", m, n)
+	}
+}
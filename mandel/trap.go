@@ -2,6 +2,7 @@ package mandel
 
 import (
 	"math"
+	"math/cmplx"
 
 	"github.com/karlek/wasabi/fractal"
 )
@@ -15,20 +16,21 @@ func OrbitTrap(z, c complex128, frac *fractal.Fractal, trap func(complex128) flo
 	// We can't assume bulb convergence since we're interested in the orbit
 	// trap functions value.
 
-	// Saved value for cycle-detection.
-	var bfract complex128
+	// Detector of periodic orbits.
+	cycle := NewCycle(frac)
 
 	// See if the complex function diverges before we reach our iteration
 	// count.
+	step := frac.Step()
 	var i int64
 	for i = 0; i < frac.Iterations; i++ {
-		z = frac.Func(z, c, frac.Coef)
+		z = step(z, c, frac.Coef)
 		// Calculate and maybe save the distance of our new point.
 		if newDist := trap(z); dist > newDist {
 			dist = newDist
 			closest = z
 		}
-		if IsCycle(z, &bfract, i) {
+		if cycle.Found(z, i) {
 			return math.Sqrt(dist), closest
 		}
 
@@ -42,14 +44,49 @@ func OrbitTrap(z, c complex128, frac *fractal.Fractal, trap func(complex128) flo
 	return math.Sqrt(dist), closest
 }
 
-// Pickover calculates the distance between the point and the coordinate axis.
+// Trapped returns a registrer which registers the orbits of reg that come
+// within the distance of the trap, where the trap returns the squared distance
+// to a point like the traps of OrbitTrap. If weighted, the intensity of the
+// orbits falls linearly from one on the trap to zero at the distance.
+func Trapped(reg Registrer, trap func(complex128) float64, distance float64, weighted bool) Registrer {
+	return func(z, c complex128, orbit *fractal.Orbit, frac *fractal.Fractal) int64 {
+		n := reg(z, c, orbit, frac)
+		if n == -1 {
+			return -1
+		}
+		closest := math.MaxFloat64
+		for _, p := range orbit.Points[:n] {
+			closest = math.Min(closest, trap(p))
+		}
+		dist := math.Sqrt(closest)
+		if dist > distance {
+			orbit.Outcome = fractal.Untrapped
+			return -1
+		}
+		if weighted {
+			orbit.Intensity = 1 - dist/distance
+		}
+		return n
+	}
+}
+
+// Pickover calculates the squared distance between the point and the
+// coordinate axes moved by -p.
 func Pickover(p complex128) func(complex128) float64 {
 	return func(z complex128) float64 {
 		// Distance to y-axis.
 		xd := math.Abs(real(z) + real(p))
 		// Distance to x-axis.
 		yd := math.Abs(imag(z) + imag(p))
-		return math.Min(xd, yd)
+		return math.Min(xd*xd, yd*yd)
+	}
+}
+
+// Circle returns the squared distance between the point z and the circle.
+func Circle(center complex128, radius float64) func(complex128) float64 {
+	return func(z complex128) float64 {
+		d := cmplx.Abs(z-center) - radius
+		return d * d
 	}
 }
 
@@ -68,24 +105,14 @@ func Point(trap complex128) func(complex128) float64 {
 	}
 }
 
-// DistToLine returns the distance between the point z and a line function
+// DistToLine returns the squared distance between the point z and a line
 // specified by the direction of the line and a point on the line.
 func DistToLine(z, p0, dir complex128) float64 {
-	// Dot product.
-	projLen := real(z)*real(dir) + imag(z)*imag(dir)
-	// Parameter on line.
-	t := sign(real(dir)) * sign(imag(dir)) * projLen / (math.Abs(real(dir)) + math.Abs(imag(dir)))
-	// Point on line closest to our point z.
-	p := p0 + complex(real(dir)*t, imag(dir)*t)
+	// Vector from the point on the line to our point z.
+	v := z - p0
+	// Parameter of the projection of v onto the line.
+	t := (real(v)*real(dir) + imag(v)*imag(dir)) / abs(dir)
 	// Vector between the closest point on the line and the point.
-	n := z - p
+	n := v - complex(real(dir)*t, imag(dir)*t)
 	return abs(n)
 }
-
-// sign returns -1 for negative numbers or 1 for positive numbers.
-func sign(f float64) float64 {
-	if f < 0 {
-		return -1
-	}
-	return 1
-}
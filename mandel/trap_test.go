@@ -0,0 +1,53 @@
+package mandel
+
+import (
+	"math"
+	"testing"
+
+	"github.com/karlek/wasabi/fractal"
+)
+
+func TestTraps(t *testing.T) {
+	golden := []struct {
+		name string
+		trap func(complex128) float64
+		z    complex128
+		dist float64
+	}{
+		{"point", Point(complex(1, 1)), complex(4, 5), 5},
+		{"circle", Circle(0, 1), complex(0, -3), 2},
+		{"circle inside", Circle(complex(1, 0), 1), complex(1.5, 0), 0.5},
+		{"line", Line(complex(1, 0), complex(1, -1)), complex(0, 1), 0},
+		{"line", Line(complex(0, 1), complex(2, 0)), complex(-3, 4), 3},
+		{"diagonal", Line(0, complex(1, 1)), complex(1, 0), math.Sqrt(0.5)},
+		{"pickover", Pickover(complex(-1, -1)), complex(1.25, 3), 0.25},
+	}
+	for _, g := range golden {
+		if got := math.Sqrt(g.trap(g.z)); math.Abs(got-g.dist) > 1e-12 {
+			t.Errorf("%s: distance to %v is %v, want %v", g.name, g.z, got, g.dist)
+		}
+	}
+}
+
+func TestTrapped(t *testing.T) {
+	frac := &fractal.Fractal{
+		Iterations: 100,
+		Bailout:    4,
+		Coef:       complex(1, 0),
+		Func:       Mandelbrot,
+	}
+	// The orbit of 0.5 is 0.5, 0.75, 1.0625, 1.62890625 and then escapes.
+	c := complex(0.5, 0)
+	reg := Trapped(Escaped, Point(complex(1, 0)), 0.2, true)
+	orbit := fractal.NewOrbit(frac.Iterations)
+	if n := reg(0, c, orbit, frac); n == -1 {
+		t.Fatalf("orbit of %v wasn't registered, outcome %v", c, orbit.Outcome)
+	}
+	if want := 1 - 0.0625/0.2; math.Abs(orbit.Intensity-want) > 1e-12 {
+		t.Errorf("intensity %v, want %v", orbit.Intensity, want)
+	}
+	reg = Trapped(Escaped, Point(complex(0, 1)), 0.2, false)
+	if n := reg(0, c, orbit, frac); n != -1 || orbit.Outcome != fractal.Untrapped {
+		t.Errorf("orbit of %v was registered with %d points, outcome %v", c, n, orbit.Outcome)
+	}
+}
@@ -0,0 +1,126 @@
+// Package perturb renders deep zooms of the mandelbrot with perturbation
+// theory. A single reference orbit is iterated with math/big at the precision
+// of the zoom, while the orbit of each pixel is iterated as a small delta from
+// the reference orbit in float64:
+//
+//	Z + z' = (Z + z)^2 + C + c   ->   z' = 2Zz + z^2 + c
+//
+// The deltas, and thereby the zooms, are limited by the range of float64 to
+// about 1e300.
+//
+// Credits: K. I. Martin, "Superfractalthing Maths", 2013, and Zhuoran's
+// rebasing, 2021.
+package perturb
+
+import (
+	"math"
+	"math/big"
+)
+
+// Reference is the orbit of the reference point C, iterated in high precision
+// and rounded to complex128.
+type Reference struct {
+	Orbit []complex128 // The points Z_0 = 0, Z_1 = C, ... up to and including the first point outside the bailout.
+}
+
+// Precision returns the number of bits needed for the reference point and
+// orbit at the zoom.
+func Precision(zoom float64) uint {
+	return uint(math.Max(0, math.Log2(zoom))) + 64
+}
+
+// ParseCenter parses the reference point from decimal strings with the
+// precision of the zoom.
+func ParseCenter(re, im string, zoom float64) (*big.Float, *big.Float, error) {
+	prec := Precision(zoom)
+	cr, _, err := big.ParseFloat(re, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, nil, err
+	}
+	ci, _, err := big.ParseFloat(im, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cr, ci, nil
+}
+
+// NewReference iterates the orbit of the point cr + ci*i with the precision of
+// the point, until it leaves the squared bailout radius or for the number of
+// iterations.
+func NewReference(cr, ci *big.Float, iterations int64, bailout float64) *Reference {
+	prec := cr.Prec()
+	if ci.Prec() > prec {
+		prec = ci.Prec()
+	}
+	num := func() *big.Float { return new(big.Float).SetPrec(prec) }
+	zr, zi := num(), num()
+	rr, ii, ri := num(), num(), num()
+
+	ref := &Reference{Orbit: make([]complex128, 1, iterations+1)}
+	for i := int64(0); i < iterations; i++ {
+		// z = (zr^2 - zi^2 + cr) + (2*zr*zi + ci)i
+		rr.Mul(zr, zr)
+		ii.Mul(zi, zi)
+		ri.Mul(zr, zi)
+		zr.Sub(rr, ii).Add(zr, cr)
+		zi.Add(ri, ri).Add(zi, ci)
+
+		x, _ := zr.Float64()
+		y, _ := zi.Float64()
+		ref.Orbit = append(ref.Orbit, complex(x, y))
+		if x*x+y*y > bailout {
+			break
+		}
+	}
+	return ref
+}
+
+// Escape iterates the orbit of the point C + dc, and returns the first point
+// outside the squared bailout radius and the number of iterations it took, or
+// -1 if it didn't escape, like mandel.EscapedLast.
+//
+// The delta is rebased onto the start of the reference orbit when the orbit of
+// the point comes closer to zero than the delta, where the delta would lose its
+// precision and glitch, or when the reference orbit ends.
+func (ref *Reference) Escape(dc complex128, iterations int64, bailout float64) (complex128, int64) {
+	var dz, z complex128
+	m, last := 0, len(ref.Orbit)-1
+	for i := int64(0); i < iterations; i++ {
+		dz = (2*ref.Orbit[m]+dz)*dz + dc
+		m++
+		z = ref.Orbit[m] + dz
+		if abs(z) > bailout {
+			return z, i
+		}
+		if abs(z) < abs(dz) || m == last {
+			dz, m = z, 0
+		}
+	}
+	return z, -1
+}
+
+// EscapeDerivative is Escape which also returns the derivative dz/dc of the
+// first point outside the bailout, for distance estimation. The derivative
+// isn't small, so it's iterated from the full points of the orbit.
+func (ref *Reference) EscapeDerivative(dc complex128, iterations int64, bailout float64) (complex128, complex128, int64) {
+	var dz, z, der complex128
+	m, last := 0, len(ref.Orbit)-1
+	for i := int64(0); i < iterations; i++ {
+		der = 2*(ref.Orbit[m]+dz)*der + 1
+		dz = (2*ref.Orbit[m]+dz)*dz + dc
+		m++
+		z = ref.Orbit[m] + dz
+		if abs(z) > bailout {
+			return z, der, i
+		}
+		if abs(z) < abs(dz) || m == last {
+			dz, m = z, 0
+		}
+	}
+	return z, der, -1
+}
+
+// abs returns the squared absolute value of z.
+func abs(z complex128) float64 {
+	return real(z)*real(z) + imag(z)*imag(z)
+}
@@ -0,0 +1,66 @@
+package perturb
+
+import (
+	"math/big"
+	"testing"
+)
+
+// escape iterates the orbit of the point cr + ci*i with big.Float and returns
+// the number of iterations until it escaped, or -1.
+func escape(cr, ci *big.Float, iterations int64, bailout float64) int64 {
+	prec := cr.Prec()
+	num := func() *big.Float { return new(big.Float).SetPrec(prec) }
+	zr, zi, rr, ii, ri := num(), num(), num(), num(), num()
+	for i := int64(0); i < iterations; i++ {
+		rr.Mul(zr, zr)
+		ii.Mul(zi, zi)
+		ri.Mul(zr, zi)
+		zr.Sub(rr, ii).Add(zr, cr)
+		zi.Add(ri, ri).Add(zi, ci)
+		x, _ := zr.Float64()
+		y, _ := zi.Float64()
+		if x*x+y*y > bailout {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestEscape(t *testing.T) {
+	golden := []struct {
+		re, im     string
+		zoom       float64
+		iterations int64
+	}{
+		{"-0.75", "0.1", 1, 100},
+		{"-1.7490000000000000001", "0.0000000000000000001", 1e6, 500},
+		// The tip of the antenna, where the escape times depend on every digit.
+		{"-1.9999999999999999999999999999995", "0.0000000000000000000000000000001", 1e30, 1000},
+	}
+	const n, bailout = 16, 16
+	for _, g := range golden {
+		cr, ci, err := ParseCenter(g.re, g.im, g.zoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := NewReference(cr, ci, g.iterations, bailout)
+		var mismatches int
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				dc := complex(2/g.zoom*(2*float64(i)/n-1), 2/g.zoom*(2*float64(j)/n-1))
+				_, got := ref.Escape(dc, g.iterations, bailout)
+
+				pr := new(big.Float).SetPrec(cr.Prec()).SetFloat64(real(dc))
+				pi := new(big.Float).SetPrec(cr.Prec()).SetFloat64(imag(dc))
+				want := escape(pr.Add(pr, cr), pi.Add(pi, ci), g.iterations, bailout)
+				if got != want {
+					mismatches++
+				}
+			}
+		}
+		// Points on the boundary may differ by rounding.
+		if mismatches > n*n/50 {
+			t.Errorf("%s%+si at zoom %g: %d of %d pixels differ from the exact escape times", g.re, g.im, g.zoom, mismatches, n*n)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package progress reports the progress of renders to terminals, log
+// collectors or nowhere at all.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event is the progress of a render at a point in time.
+type Event struct {
+	Tries    int64         // Number of completed orbit attempts.
+	Max      int64         // Number of orbit attempts of the whole render.
+	Rate     float64       // Orbit attempts per second.
+	HitRatio float64       // Share of the orbit attempts which registered any points.
+	Elapsed  time.Duration // Time since the render was started or resumed.
+	ETA      time.Duration // Estimated time until the render is done.
+	Done     bool          // The render has stopped.
+}
+
+// Reporter receives the progress events of a render.
+type Reporter interface {
+	Report(e Event)
+}
+
+// Silent is a reporter which ignores all events.
+type Silent struct{}
+
+// Report ignores the event.
+func (Silent) Report(e Event) {}
+
+// barWidth is the number of characters of the terminal progress bar.
+const barWidth = 40
+
+// Terminal is a reporter which draws a progress bar on a terminal.
+type Terminal struct {
+	w io.Writer
+}
+
+// NewTerminal returns a reporter which draws a progress bar on the terminal w.
+func NewTerminal(w io.Writer) *Terminal {
+	return &Terminal{w: w}
+}
+
+// Report redraws the progress bar with the event.
+func (t *Terminal) Report(e Event) {
+	part := 1.0
+	if e.Max > 0 {
+		part = float64(e.Tries) / float64(e.Max)
+	}
+	if part > 1 {
+		part = 1
+	}
+	n := int(part * barWidth)
+	bar := strings.Repeat("=", n) + strings.Repeat(" ", barWidth-n)
+	fmt.Fprintf(t.w, "\r[%s] %3d%% %.3g orbits/s, hit ratio %.3f, ETA %v ", bar, int(part*100), e.Rate, e.HitRatio, e.ETA.Round(time.Second))
+	if e.Done {
+		fmt.Fprintln(t.w)
+	}
+}
+
+// JSON is a reporter which writes each event as a line of JSON, for batch
+// schedulers and log collectors.
+type JSON struct {
+	enc *json.Encoder
+}
+
+// NewJSON returns a reporter which writes the events to w.
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{enc: json.NewEncoder(w)}
+}
+
+// Report writes the event as a line of JSON. Durations are given in seconds.
+// Failed writes are logged, since they don't stop the render.
+func (j *JSON) Report(e Event) {
+	err := j.enc.Encode(struct {
+		Tries    int64   `json:"tries"`
+		Max      int64   `json:"max"`
+		Rate     float64 `json:"rate"`
+		HitRatio float64 `json:"hitRatio"`
+		Elapsed  float64 `json:"elapsed"`
+		ETA      float64 `json:"eta"`
+		Done     bool    `json:"done"`
+	}{e.Tries, e.Max, e.Rate, e.HitRatio, e.Elapsed.Seconds(), e.ETA.Seconds(), e.Done})
+	if err != nil {
+		logrus.Warnln("unable to report progress:", err)
+	}
+}